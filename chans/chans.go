@@ -78,6 +78,11 @@ func AfterContext(ctx context.Context, fn func(), cancelable ...bool) (cancel fu
 
 // AfterTime will invoke fn after d, but cancel when ctx done or invoke cancel function
 func AfterTimeWithContext(ctx context.Context, d time.Duration, fn func(), cancelable ...bool) (cancel func()) {
+	return AfterTimeWithClock(ctx, SystemClock, d, fn, cancelable...)
+}
+
+// AfterTimeWithClock is AfterTimeWithContext on the clock.
+func AfterTimeWithClock(ctx context.Context, clock Clock, d time.Duration, fn func(), cancelable ...bool) (cancel func()) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -87,11 +92,13 @@ func AfterTimeWithContext(ctx context.Context, d time.Duration, fn func(), cance
 		cancel = nopCancel
 	}
 
+	t := clock.NewTimer(d)
 	go func() {
+		defer t.Stop()
 		select {
 		case <-ctx.Done():
 			return
-		case <-time.After(d):
+		case <-t.C():
 			fn()
 		}
 	}()
@@ -106,14 +113,22 @@ func AfterTime(d time.Duration, fn func(), cancelable ...bool) (cancel func()) {
 
 // Sleep sleeps for the specified duration. It returns false if the context is canceled.
 func Sleep(ctx context.Context, d time.Duration) (r bool) {
+	return SleepWithClock(ctx, SystemClock, d)
+}
+
+// SleepWithClock is Sleep on the clock.
+func SleepWithClock(ctx context.Context, clock Clock, d time.Duration) (r bool) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
+	t := clock.NewTimer(d)
+	defer t.Stop()
+
 	select {
 	case <-ctx.Done():
 		r = false
-	case <-time.After(d):
+	case <-t.C():
 		r = true
 	}
 
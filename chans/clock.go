@@ -0,0 +1,180 @@
+package chans
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock is the source of the time and timers, FakeClock replaces it in tests.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	AfterFunc(d time.Duration, fn func()) Timer
+}
+
+// Timer is a timer of a Clock, as time.Timer.
+type Timer interface {
+	// C is the channel of the time the timer fires, nil for the timers of AfterFunc.
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// SystemClock is the Clock of the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+func (systemClock) AfterFunc(d time.Duration, fn func()) Timer {
+	return systemTimer{time.AfterFunc(d, fn)}
+}
+
+type systemTimer struct{ *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+// FakeClock is a Clock only moves by Advance and Set, the timers fire as the time moves.
+type FakeClock struct {
+	now     time.Time
+	timers  []*fakeTimer
+	changed chan struct{} // closed and replaced when the timers changed
+	mu      sync.Mutex
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, changed: make(chan struct{})}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{clock: c, fn: fn}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the time forward by d, and fires the timers due.
+func (c *FakeClock) Advance(d time.Duration) { c.Set(c.Now().Add(d)) }
+
+// Set moves the time to now, and fires the timers due in order of their times.
+func (c *FakeClock) Set(now time.Time) {
+	for {
+		c.mu.Lock()
+		if len(c.timers) == 0 || c.timers[0].when.After(now) {
+			c.now = now
+			c.mu.Unlock()
+			return
+		}
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		c.notify()
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+		fired := c.now
+		c.mu.Unlock()
+
+		t.fire(fired) // the current time, as time.Timer
+	}
+}
+
+// Timers returns the number of the pending timers.
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntil waits until there are at least n pending timers, e.g. the code under test
+// is waiting on a timer.
+func (c *FakeClock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		pending, changed := len(c.timers), c.changed
+		c.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// notify wakes BlockUntil, c.mu must be held.
+func (c *FakeClock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	ch    chan time.Time
+	fn    func()
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) fire(now time.Time) {
+	if t.fn != nil {
+		go t.fn()
+		return
+	}
+	select {
+	case t.ch <- now:
+	default:
+	}
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := slices.Index(c.timers, t)
+	if i >= 0 {
+		c.timers = slices.Delete(c.timers, i, i+1)
+		c.notify()
+	}
+	return i >= 0
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	active := t.Stop()
+
+	c := t.clock
+	c.mu.Lock()
+	fire := d <= 0
+	t.when = c.now.Add(max(d, 0))
+	if !fire {
+		// Keep the timers ordered by the time, so the same time fires in the order of creation.
+		i, _ := slices.BinarySearchFunc(c.timers, t.when, func(e *fakeTimer, when time.Time) int {
+			if e.when.After(when) {
+				return 1
+			}
+			return -1
+		})
+		c.timers = slices.Insert(c.timers, i, t)
+		c.notify()
+	}
+	c.mu.Unlock()
+
+	if fire {
+		t.fire(t.when)
+	}
+	return active
+}
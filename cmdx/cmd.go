@@ -10,6 +10,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/cnk3x/gox/chans"
@@ -20,10 +22,12 @@ import (
 )
 
 var (
-	ErrDone    = errors.New("done")
-	ErrStop    = errors.New("user stop")
-	ErrRestart = errors.New("user restart")
-	ErrStatus  = errors.New("error status")
+	ErrDone     = errors.New("done")
+	ErrStop     = errors.New("user stop")
+	ErrRestart  = errors.New("user restart")
+	ErrStatus   = errors.New("error status")
+	ErrUnready  = errors.New("not ready before the deadline")
+	ErrWatchdog = errors.New("watchdog timeout")
 )
 
 type Options struct {
@@ -34,13 +38,69 @@ type Options struct {
 	RestartDelay time.Duration `json:"restart_delay,omitempty" yaml:"restart_delay,omitempty"`
 	Logger       *Logger       `json:"logger,omitempty" yaml:"logger,omitempty"`
 
+	// Verbatim passes Execute, Args and Env as they are, without the expansion of the {dir}
+	// and {ENV} tags, e.g. the shell commands of a crontab.
+	Verbatim bool `json:"verbatim,omitempty" yaml:"verbatim,omitempty"`
+
+	// Subreaper (linux only) makes the supervisor a child subreaper, orphaned descendants
+	// are reaped, and any descendant still alive is killed when the program stops.
+	Subreaper bool `json:"subreaper,omitempty" yaml:"subreaper,omitempty"`
+
+	// ReadyPattern is a regexp matched against the stdout and stderr lines, if set, the
+	// program stays starting until a line matches.
+	ReadyPattern string `json:"ready_pattern,omitempty" yaml:"ready_pattern,omitempty"`
+	// ReadyTimeout is the deadline of ReadyPattern, the program fails with ErrUnready if no
+	// line matches in time. Zero means no deadline.
+	ReadyTimeout time.Duration `json:"ready_timeout,omitempty" yaml:"ready_timeout,omitempty"`
+	// ReadyRestart restarts the program on ErrUnready instead of stopping it.
+	ReadyRestart bool `json:"ready_restart,omitempty" yaml:"ready_restart,omitempty"`
+
+	// Notify supports the systemd notify protocol, NOTIFY_SOCKET is passed to the program,
+	// and the program stays starting until it sends READY=1 (or ReadyPattern matches).
+	Notify bool `json:"notify,omitempty" yaml:"notify,omitempty"`
+	// Watchdog is passed as WATCHDOG_USEC when Notify is set, once ready, the program is
+	// restarted if it does not send WATCHDOG=1 within the interval.
+	Watchdog time.Duration `json:"watchdog,omitempty" yaml:"watchdog,omitempty"`
+
+	// Listen declares sockets owned by the supervisor and kept open across the runs, they
+	// are passed to the program by socket activation (LISTEN_FDS, LISTEN_PID, LISTEN_FDNAMES)
+	// from fd 3, e.g. ":8080", "tcp://127.0.0.1:8080#http", "unix:///run/app.sock#api".
+	// The program is exec'd by /bin/sh to learn its pid, with Isolation.Root the root must
+	// have /bin/sh, else the run fails.
+	Listen []string `json:"listen,omitempty" yaml:"listen,omitempty"`
+	// RestartMode is how Restart replaces the run, RestartStopFirst (default) or RestartStartFirst.
+	RestartMode string `json:"restart_mode,omitempty" yaml:"restart_mode,omitempty"`
+
+	// Schedules are actions run on cron specs, e.g. a periodic restart or a nightly maintenance.
+	// With a start action, supervision does not end when the program stops, but when ctx is done.
+	Schedules []Schedule `json:"schedules,omitempty" yaml:"schedules,omitempty"`
+
+	// Isolation (linux only) runs the program in new namespaces and root.
+	Isolation *Isolation `json:"isolation,omitempty" yaml:"isolation,omitempty"`
+
+	// History is the path of a JSON-lines file, a RunRecord is appended when each run ends.
+	History string `json:"history,omitempty" yaml:"history,omitempty"`
+	// HistoryTail is the number of output lines kept in a RunRecord, default 20, -1 for none.
+	HistoryTail int `json:"history_tail,omitempty" yaml:"history_tail,omitempty"`
+	// HistoryMax is the number of records kept in the History file, see History.Max.
+	HistoryMax int `json:"history_max,omitempty" yaml:"history_max,omitempty"`
+
 	PreStart []func(c *exec.Cmd) error `json:"-" yaml:"-"`
 }
 
+const (
+	RestartStopFirst  = "stop-first"  // stop the old run, then start the new one
+	RestartStartFirst = "start-first" // start the new run, stop the old one once the new one is running
+)
+
 type Logger struct {
 	*RotateOptions `json:",inline" yaml:",inline"`
 	Stderr         *RotateOptions `json:"stderr,omitempty" yaml:"stderr,omitempty"`
 	Stdout         *RotateOptions `json:"stdout,omitempty" yaml:"stdout,omitempty"`
+
+	// Merge writes stdout and stderr lines to the inline options, tagged with [out] and [err],
+	// Stdout and Stderr are ignored.
+	Merge bool `json:"merge,omitempty" yaml:"merge,omitempty"`
 }
 
 // Status is a status code.
@@ -78,17 +138,71 @@ type Result struct {
 
 	Restart func()
 	Stop    func()
-	Wait    func()
+	Start   func() // start the program again if stopped, see Options.Schedules
+
+	// Wait waits until supervision ended, it returns the final error (Err) or ctx.Err(),
+	// nil after Stop.
+	Wait func(ctx context.Context) error
+	// Done returns a channel closed when supervision ended.
+	Done func() <-chan struct{}
+	// Current returns the current run, each restart or start begins a new generation.
+	Current func() Generation
 
 	Status Status // status
 	Pid    int    // pid
 	Exit   int    // exit code
-	Err    error  // error
+	Err    error  // error of the last run, nil if it was stopped by Stop
+
+	Message string // status message sent by the program, see Options.Notify
+
+	Restarts int // restart count
+	LastExit int // exit code of the last exited run, -1 if killed by a signal
+
+	History *History // run records, nil if Options.History is not set
 
 	StartTs int64
 	StopTs  int64
 
 	Changed <-chan Status
+
+	mu sync.Mutex // guards the state fields written by the supervisor, see Stat
+}
+
+// Stat is a consistent snapshot of the state fields of a Result.
+type Stat struct {
+	Status   Status
+	Pid      int
+	Exit     int
+	Err      error
+	Message  string
+	Restarts int
+	LastExit int
+	StartTs  int64
+	StopTs   int64
+}
+
+// Stat returns the state fields under the lock, for the readers on other goroutines, e.g. Metrics.
+func (s *Result) Stat() Stat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Stat{
+		Status: s.Status, Pid: s.Pid, Exit: s.Exit, Err: s.Err, Message: s.Message,
+		Restarts: s.Restarts, LastExit: s.LastExit, StartTs: s.StartTs, StopTs: s.StopTs,
+	}
+}
+
+// set updates the state fields under the lock.
+func (s *Result) set(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn()
+}
+
+// Generation is a run of the program.
+type Generation struct {
+	ID      uint64          // increases on each run, starting from 1
+	Running <-chan struct{} // closed when the run is running, after readiness if configured
+	Done    <-chan struct{} // closed when the run ended
 }
 
 type Option func(*Options)
@@ -109,56 +223,151 @@ func cmdRun(ctx context.Context, options Options) (s *Result) {
 	var (
 		allDone, closeAllDone = chans.StructChan()
 		statusc, closeStatusc = chans.MakeChan[Status](5)
-		pDone                 <-chan struct{}
 		pCancel               context.CancelFunc
+		sockets               listeners
+		cur                   Generation
+		curMu                 sync.Mutex
 	)
 
 	s = &Result{Changed: statusc}
+	if options.History != "" {
+		s.History = NewHistory(options.History)
+		s.History.Max = options.HistoryMax
+	}
+	cur.Done = allDone // no run yet
 
-	statusUpdate := func(status Status) {
-		s.Status = status
+	// a program with a start schedule may start again after stopped, until ctx is done.
+	startable := slices.ContainsFunc(options.Schedules, func(it Schedule) bool { return strs.Lower(it.Action) == ActionStart })
+	end := func() {
+		closeAllDone()
+		closeStatusc()
+	}
+
+	var statusMu sync.Mutex
+	statusUpdate := func(status Status, from ...Status) {
+		statusMu.Lock()
+		defer statusMu.Unlock()
+		if s.Status == StatusStopped || len(from) > 0 && !slices.Contains(from, s.Status) {
+			return
+		}
+
+		s.set(func() { s.Status = status })
 		select {
 		case statusc <- status:
 		default:
 			_ = <-statusc
 			statusc <- status
 		}
-		if status == StatusStopped {
-			closeAllDone()
-			closeStatusc()
+		if status == StatusStopped && (!startable || ctx.Err() != nil) {
+			end()
 		}
 	}
 
-	run := func() {
-		s.Status = StatusUnknown
-		s.Err = nil
-		s.Exit = 0
-		s.StopTs = 0
-		s.StartTs = time.Now().UnixNano()
+	// cancelCurrent returns the cancel of the current run.
+	cancelCurrent := func() context.CancelFunc {
+		curMu.Lock()
+		defer curMu.Unlock()
+		return pCancel
+	}
 
-		var (
-			dir      = filepath.Clean(options.Dir)
-			replArgs = map[string]string{"dir": dir}
-			execute  = filepath.Clean(strRepl(options.Execute, replArgs))
-			args     = strReplAll(options.Args, replArgs)
-			env      = Env(os.Environ()).Sets(strReplAll(options.Env, replArgs)...)
-		)
+	s.Current = func() Generation {
+		curMu.Lock()
+		defer curMu.Unlock()
+		return cur
+	}
 
+	run := func() {
 		done, closeDone := chans.StructChan()
-		pDone = done
+		running, closeRunning := chans.StructChan()
+
+		curMu.Lock()
+		cur = Generation{ID: cur.ID + 1, Running: running, Done: done}
+		gen := cur.ID
+		curMu.Unlock()
+		current := func() bool { return s.Current().ID == gen }
+
+		startTs := time.Now().UnixNano()
+		s.set(func() {
+			s.Status = StatusUnknown
+			s.Err = nil
+			s.Message = ""
+			s.Exit = 0
+			s.StopTs = 0
+			s.StartTs = startTs
+		})
+
+		dir := filepath.Clean(options.Dir)
+		execute, args, env := options.Execute, options.Args, options.Env
+		if !options.Verbatim {
+			replArgs := map[string]string{"dir": dir}
+			execute, args, env = strRepl(execute, replArgs), strReplAll(args, replArgs), strReplAll(env, replArgs)
+		}
+		execute, env = filepath.Clean(execute), Env(os.Environ()).Sets(env...)
 
 		ctx, cancel := context.WithCancel(ctx)
+		curMu.Lock()
 		pCancel = cancel
+		curMu.Unlock()
 		chans.AfterChan(done, cancel)
 
+		var (
+			desc    *descendants
+			notify  *notifySocket
+			failure error // the reason the supervisor stopped this run, kept in Result.Err
+			tail    *tailLines
+			waited  func()      // releases the pid of the process to the reaper after c.Wait
+			unbind  = func() {} // undoes the isolation binds once the process exited
+		)
+
+		fail := func(err error, restart bool) {
+			if !current() {
+				return
+			}
+			slog.Warn("[cmdx] program failed", "command", s.Command, "err", err, "restart", restart)
+			failure = err
+			if restart {
+				s.Restart()
+				return
+			}
+			s.Stop()
+		}
+
 		c := setProcessGroup(exec.CommandContext(ctx, execute, args...))
 		c.Dir, c.Env = dir, env
-		c.Cancel = func() error { return terminateProcess(c.Process.Pid) }
+		c.Cancel = func() error {
+			err := terminateProcess(c.Process.Pid)
+			desc.terminate()
+			return err
+		}
+
+		record := func(reason string, err error) {
+			if s.History == nil {
+				return
+			}
+			r := RunRecord{Command: s.Command, Start: time.Unix(0, startTs), Stop: time.Now(), Exit: c.ProcessState.ExitCode(), Reason: reason}
+			if c.Process != nil {
+				r.Pid = c.Process.Pid
+			}
+			if err != nil {
+				r.Err = err.Error()
+			}
+			if tail != nil {
+				r.Tail = tail.Lines()
+			}
+			if err := s.History.Append(r); err != nil {
+				slog.Warn("[cmdx] append history", "path", s.History.Path, "err", err)
+			}
+		}
 
 		if options.Logger != nil {
 			loggerFactory := createLoggerFactory()
-			c.Stdout = loggerFactory.Create(options.Logger.Stdout, options.Logger.RotateOptions)
-			c.Stderr = loggerFactory.Create(options.Logger.Stderr, options.Logger.RotateOptions)
+			if options.Logger.Merge {
+				c.Stdout = loggerFactory.CreateTag("[out]", options.Logger.RotateOptions)
+				c.Stderr = loggerFactory.CreateTag("[err]", options.Logger.RotateOptions)
+			} else {
+				c.Stdout = loggerFactory.Create(options.Logger.Stdout, options.Logger.RotateOptions)
+				c.Stderr = loggerFactory.Create(options.Logger.Stderr, options.Logger.RotateOptions)
+			}
 			chans.AfterChan(done, fss.NoErr(loggerFactory))
 		}
 
@@ -169,46 +378,168 @@ func cmdRun(ctx context.Context, options Options) (s *Result) {
 		}
 
 		started, closeStarted := chans.StructChan()
+		ready, closeReady := chans.StructChan()
+		setRunning := func() {
+			statusUpdate(StatusRunning, StatusStarting)
+			closeRunning()
+		}
 		go func() {
 			defer closeDone()
 
 			err := func() (err error) {
 				defer closeStarted()
+				if options.Subreaper {
+					if err = setSubreaper(); err != nil {
+						return
+					}
+					desc = newDescendants()
+					c.Env = append(c.Env, desc.env())
+				}
+
+				if options.ReadyPattern != "" {
+					var ready *readyMatcher
+					if ready, err = newReadyMatcher(options.ReadyPattern, closeReady); err != nil {
+						return
+					}
+					c.Stdout, c.Stderr = ready.Wrap(c.Stdout), ready.Wrap(c.Stderr)
+				}
+
+				if s.History != nil && options.HistoryTail >= 0 {
+					tail = &tailLines{max: cmp.Or(options.HistoryTail, 20)}
+					c.Stdout, c.Stderr = tail.Wrap(c.Stdout), tail.Wrap(c.Stderr)
+				}
+
+				if options.Notify {
+					if notify, err = listenNotify(); err != nil {
+						return
+					}
+					c.Env = append(c.Env, notify.Env(options.Watchdog)...)
+					chans.AfterChan(done, fss.NoErr(notify))
+				}
+
+				if len(sockets) > 0 {
+					var files []*os.File
+					if files, err = sockets.Files(); err != nil {
+						return
+					}
+					defer closeFiles(files)
+
+					var root string
+					if options.Isolation != nil {
+						root = options.Isolation.Root
+					}
+					if err = setListenPid(c, root); err != nil {
+						return
+					}
+					c.ExtraFiles = files
+					c.Env = append(c.Env, sockets.Env()...)
+				}
+
+				if options.Isolation != nil {
+					if unbind, err = isolate(c, options.Isolation); err != nil {
+						return
+					}
+				}
+
 				for _, ps := range options.PreStart {
 					if err = ps(c); err != nil {
 						return
 					}
 				}
 
-				if err = c.Start(); err != nil {
+				if waited, err = startRoot(c); err != nil {
+					if options.Isolation != nil {
+						err = isolationError(err)
+					}
 					return
 				}
 
-				s.Pid = c.Process.Pid
+				s.set(func() { s.Pid = c.Process.Pid })
+				desc.track(c.Process.Pid)
 				return
 			}()
 
-			if s.Err = err; s.Err != nil {
+			if s.set(func() { s.Err = err }); err != nil {
+				unbind()
+				record(ReasonFailed, err)
 				statusUpdate(StatusStopped)
 				return
 			}
 
-			statusUpdate(StatusRunning)
+			if notify != nil {
+				go notify.Serve(s, options.Watchdog, closeReady, fail)
+			}
+
+			if options.ReadyPattern == "" && !options.Notify {
+				setRunning()
+			} else {
+				go func() {
+					var deadline <-chan time.Time
+					if options.ReadyTimeout > 0 {
+						t := time.NewTimer(options.ReadyTimeout)
+						defer t.Stop()
+						deadline = t.C
+					}
+
+					select {
+					case <-ready:
+						setRunning()
+					case <-deadline:
+						fail(ErrUnready, options.ReadyRestart)
+					case <-done:
+					}
+				}()
+			}
 
 			err = c.Wait()
+			waited()
+			unbind()
+			desc.kill()
+			if c.ProcessState != nil {
+				s.set(func() { s.LastExit = c.ProcessState.ExitCode() })
+			}
+
+			switch {
+			case failure != nil:
+				record(ReasonFailed, failure)
+			case !current() || s.Status == StatusRestarting:
+				record(ReasonRestart, err)
+			case s.Status == StatusStopping:
+				record(ReasonStop, err)
+			case err != nil:
+				record(ReasonCrash, err)
+			default:
+				record(ReasonExit, nil)
+			}
+
+			if !current() {
+				slog.Debug("[cmdx] previous run exited", "command", s.Command, "err", err)
+				return
+			}
+
 			if err != nil {
 				if s.Status == StatusRestarting {
 					return
 				}
 
-				var ee *exec.ExitError
-				if errors.As(err, &ee) {
-					s.Exit = ee.ExitCode()
-				}
-				s.Err = err
+				s.set(func() {
+					var ee *exec.ExitError
+					if errors.As(err, &ee) {
+						s.Exit = ee.ExitCode()
+					}
+					// The exit of a user stop is not an error, e.g. "signal: terminated".
+					if s.Status != StatusStopping {
+						s.Err = err
+					}
+				})
+			}
+
+			if failure != nil {
+				s.set(func() { s.Err = failure })
 			}
 
 			if s.Status != StatusRestarting {
+				s.set(func() { s.StopTs = time.Now().UnixNano() })
 				statusUpdate(StatusStopped)
 			}
 		}()
@@ -216,24 +547,92 @@ func cmdRun(ctx context.Context, options Options) (s *Result) {
 	}
 
 	s.Restart = func() {
-		if s.Status != StatusRunning {
+		if status := s.Stat().Status; status != StatusRunning && status != StatusStarting {
 			return
 		}
 		statusUpdate(StatusRestarting)
-		pCancel()
-		<-pDone
+		s.set(func() { s.Restarts++ })
+
+		if options.RestartMode == RestartStartFirst {
+			oldCancel, old := cancelCurrent(), s.Current()
+			run()
+			select {
+			case <-s.Current().Running:
+			case <-s.Current().Done:
+			}
+			oldCancel()
+			<-old.Done
+			return
+		}
+
+		cancelCurrent()()
+		<-s.Current().Done
 		run()
 	}
 
 	s.Stop = func() {
-		if s.Status != StatusRunning {
+		if status := s.Stat().Status; status != StatusRunning && status != StatusStarting {
 			return
 		}
 		statusUpdate(StatusStopping)
-		pCancel()
+		cancelCurrent()()
 	}
 
-	s.Wait = func() { <-allDone }
+	s.Start = func() {
+		select {
+		case <-allDone:
+			return
+		default:
+		}
+		if s.Stat().Status == StatusStopped {
+			run()
+		}
+	}
+
+	s.Done = func() <-chan struct{} { return allDone }
+
+	s.Wait = func(ctx context.Context) error {
+		select {
+		case <-allDone:
+			return s.Err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var err error
+	if err = checkSinks(options.Logger); err != nil {
+		s.set(func() { s.Err = err })
+		statusUpdate(StatusStopped)
+		return
+	}
+
+	if sockets, err = listenAll(options.Listen); err != nil {
+		s.set(func() { s.Err = err })
+		statusUpdate(StatusStopped)
+		return
+	}
+	chans.AfterChan(allDone, fss.NoErr(sockets))
+
+	if len(options.Schedules) > 0 {
+		stopSchedules, err := startSchedules(s, options)
+		if err != nil {
+			s.set(func() { s.Err = err })
+			statusUpdate(StatusStopped)
+			return
+		}
+		chans.AfterChan(allDone, stopSchedules)
+	}
+
+	if startable {
+		chans.AfterContext(ctx, func() {
+			statusMu.Lock()
+			defer statusMu.Unlock()
+			if s.Status == StatusStopped {
+				end()
+			}
+		})
+	}
 
 	run()
 
@@ -243,17 +642,23 @@ func cmdRun(ctx context.Context, options Options) (s *Result) {
 /** logger **/
 
 type LoggerFactory struct {
-	create func(options ...*RotateOptions) (w io.Writer)
+	create func(tag string, options ...*RotateOptions) (w io.Writer)
 	close  func() error
 }
 
-func (f LoggerFactory) Create(options ...*RotateOptions) io.Writer { return f.create(options...) }
+func (f LoggerFactory) Create(options ...*RotateOptions) io.Writer { return f.create("", options...) }
 func (f LoggerFactory) Close() error                               { return f.close() }
 
+// CreateTag creates a writer prefixes each line with the tag.
+func (f LoggerFactory) CreateTag(tag string, options ...*RotateOptions) io.Writer {
+	return f.create(tag, options...)
+}
+
 func createLoggerFactory() (factory LoggerFactory) {
 	writers := make(map[string]io.WriteCloser, 2)
+	var lines []*lineWriter
 
-	factory.create = func(options ...*RotateOptions) (w io.Writer) {
+	factory.create = func(tag string, options ...*RotateOptions) (w io.Writer) {
 		var opts RotateOptions
 		for _, it := range options {
 			if it != nil {
@@ -261,6 +666,10 @@ func createLoggerFactory() (factory LoggerFactory) {
 				opts.MaxBackups = cmp.Or(opts.MaxBackups, it.MaxBackups)
 				opts.MaxSize = cmp.Or(opts.MaxSize, it.MaxSize)
 				opts.Std = cmp.Or(opts.Std, it.Std)
+				opts.Interval = cmp.Or(opts.Interval, strs.Lower(strs.TrimSpace(it.Interval)))
+				opts.Timestamp = cmp.Or(opts.Timestamp, it.Timestamp)
+				opts.TimeLayout = cmp.Or(opts.TimeLayout, it.TimeLayout)
+				opts.Sink = cmp.Or(opts.Sink, strs.TrimSpace(it.Sink))
 			}
 		}
 
@@ -269,7 +678,28 @@ func createLoggerFactory() (factory LoggerFactory) {
 				if w = writers[opts.Path]; w == nil {
 					w = Rotate(opts)
 					writers[opts.Path] = w.(io.WriteCloser)
-					slog.Debug("[cmdx] created rotate writer", "path", opts.Path, "size", opts.MaxSize, "backups", opts.MaxBackups)
+					slog.Debug("[cmdx] created rotate writer", "path", opts.Path, "size", opts.MaxSize, "backups", opts.MaxBackups, "interval", opts.Interval)
+				}
+			}
+		}
+
+		if opts.Sink != "" {
+			sink := writers[opts.Sink]
+			if sink == nil {
+				var err error
+				if sink, err = OpenSink(opts.Sink); err != nil {
+					slog.Warn("[cmdx] create sink writer", "sink", opts.Sink, "err", err)
+				} else {
+					writers[opts.Sink] = sink
+					slog.Debug("[cmdx] created sink writer", "sink", opts.Sink)
+				}
+			}
+
+			if sink != nil {
+				if w != nil {
+					w = io.MultiWriter(w, sink)
+				} else {
+					w = sink
 				}
 			}
 		}
@@ -292,11 +722,25 @@ func createLoggerFactory() (factory LoggerFactory) {
 				slog.Debug("[cmdx] created std writer", "std", opts.Std)
 			}
 		}
+
+		if w != nil && (opts.Timestamp || tag != "") {
+			var layout string
+			if opts.Timestamp {
+				layout = cmp.Or(opts.TimeLayout, DefaultTimeLayout)
+			}
+			lw := newLineWriter(w, layout, tag)
+			lines = append(lines, lw)
+			w = lw
+		}
 		return
 	}
 
 	factory.close = func() (err error) {
 		var errs []error
+		for _, lw := range lines {
+			errs = append(errs, lw.Close())
+		}
+		lines = nil
 		for writer := range maps.Values(writers) {
 			errs = append(errs, writer.Close())
 		}
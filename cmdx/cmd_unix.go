@@ -3,8 +3,14 @@
 package cmdx
 
 import (
+	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"syscall"
+
+	"github.com/cnk3x/gox/strs"
 )
 
 func terminateProcess(pid int) error {
@@ -21,3 +27,47 @@ func setProcessGroup(c *exec.Cmd) *exec.Cmd {
 	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	return c
 }
+
+func setListenPid(c *exec.Cmd, root string) error {
+	// LISTEN_PID must be the pid of the program, which is unknown before it is started.
+	// Exec the program by a shell which exports its own pid, exec keeps the pid.
+	if c.Err != nil {
+		return c.Err
+	}
+	if root != "" {
+		// The shell runs inside the root.
+		if _, err := os.Stat(filepath.Join(root, "bin", "sh")); err != nil {
+			return fmt.Errorf("socket activation needs /bin/sh in the isolation root: %w", err)
+		}
+	}
+	c.Args = append([]string{"sh", "-c", `export LISTEN_PID=$$; exec "$0" "$@"`, c.Path}, c.Args[1:]...)
+	c.Path = "/bin/sh"
+	return nil
+}
+
+var signals = map[string]syscall.Signal{
+	"HUP": syscall.SIGHUP, "INT": syscall.SIGINT, "QUIT": syscall.SIGQUIT, "KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1, "USR2": syscall.SIGUSR2, "TERM": syscall.SIGTERM, "ALRM": syscall.SIGALRM,
+	"CONT": syscall.SIGCONT, "STOP": syscall.SIGSTOP, "WINCH": syscall.SIGWINCH,
+}
+
+// signalProcess sends the signal to the process, by name (HUP, SIGHUP) or number.
+func signalProcess(pid int, name string) error {
+	sig, found := signals[strs.TrimPrefix(strs.Upper(strs.TrimSpace(name)), "SIG")]
+	if !found {
+		n, err := strconv.Atoi(name)
+		if err != nil {
+			return fmt.Errorf("unknown signal %q", name)
+		}
+		sig = syscall.Signal(n)
+	}
+	return syscall.Kill(pid, sig)
+}
+
+// shellCommand returns the shell running the command line, shell defaults to /bin/sh.
+func shellCommand(shell, command string) (execute string, args []string) {
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	return shell, []string{"-c", command}
+}
@@ -1,6 +1,8 @@
 package cmdx
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"syscall"
@@ -25,6 +27,22 @@ func terminateProcess(pid int) error {
 	return p.Kill()
 }
 
+func signalProcess(int, string) error {
+	return fmt.Errorf("signals are not supported on windows: %w", errors.ErrUnsupported)
+}
+
+func setListenPid(*exec.Cmd, string) error {
+	return fmt.Errorf("socket activation is not supported on windows: %w", errors.ErrUnsupported)
+}
+
+// shellCommand returns the shell running the command line, shell defaults to cmd.
+func shellCommand(shell, command string) (execute string, args []string) {
+	if shell == "" {
+		return "cmd", []string{"/C", command}
+	}
+	return shell, []string{"-c", command}
+}
+
 // // terminate terminate the process and all its children in Windows
 // func terminate(pid int) (err error) {
 // 	// Open a handle to the process with PROCESS_TERMINATE access
@@ -0,0 +1,41 @@
+package cmdx
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/cnk3x/gox/cron"
+	"github.com/cnk3x/gox/strs"
+)
+
+// CrontabRunner returns a runner of the crontab commands, each command runs once by the
+// SHELL of the crontab, with the options as the template, e.g. Dir and Logger. The commands
+// and the env are passed verbatim, see Options.Verbatim.
+func CrontabRunner(options Options) cron.CommandRunner {
+	return func(ctx context.Context, command string, env []string) error {
+		var shell string
+		if i := slices.IndexFunc(env, strs.PrefixMatch("SHELL=")); i >= 0 {
+			shell = env[i][len("SHELL="):]
+		}
+
+		once := options
+		once.Verbatim = true
+		once.Execute, once.Args = shellCommand(shell, command)
+		once.Env = append(slices.Clip(options.Env), env...)
+
+		r := Run(ctx, WithOptions(once))
+		err := r.Wait(ctx)
+		slog.Info("[cmdx] crontab", "command", command, "exit", r.Exit, "err", err)
+		return err
+	}
+}
+
+// LoadCrontab adds the jobs of the crontab file to c, the commands run by CrontabRunner.
+func LoadCrontab(c *cron.Cron, path string, options Options) error {
+	tab, err := cron.LoadCrontab(path)
+	if err != nil {
+		return err
+	}
+	return tab.Add(c, CrontabRunner(options))
+}
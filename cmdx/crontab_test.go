@@ -0,0 +1,22 @@
+package cmdx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCrontabRunnerBraces(t *testing.T) {
+	dir := t.TempDir()
+	run := CrontabRunner(Options{Dir: dir})
+
+	command := `echo a b | awk '{print $2}' > out; echo '{"k":1}' >> out; echo "$X" >> out`
+	if err := run(t.Context(), command, []string{"SHELL=/bin/sh", "X={dir}"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "out"))
+	if want := "b\n{\"k\":1}\n{dir}\n"; string(data) != want {
+		t.Errorf("out %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,213 @@
+package cmdx
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Run end reasons of RunRecord.
+const (
+	ReasonExit    = "exit"    // exited with code 0
+	ReasonCrash   = "crash"   // exited with an error, non-zero code or signal
+	ReasonStop    = "stop"    // stopped by Stop or a schedule
+	ReasonRestart = "restart" // replaced by Restart or a schedule
+	ReasonFailed  = "failed"  // failed to start, or stopped by the supervisor, e.g. ErrUnready
+)
+
+// RunRecord is a run of the program kept in the history.
+type RunRecord struct {
+	Command string    `json:"command"`
+	Start   time.Time `json:"start"`
+	Stop    time.Time `json:"stop"`
+	Pid     int       `json:"pid,omitempty"`
+	Exit    int       `json:"exit"`
+	Reason  string    `json:"reason"`
+	Err     string    `json:"err,omitempty"`
+	Tail    []string  `json:"tail,omitempty"` // last lines of the output
+}
+
+// DefaultHistoryMax is the number of the records kept by a History by default.
+const DefaultHistoryMax = 1000
+
+// History is a JSON-lines file of run records, it survives restarts of the supervisor.
+// The file is compacted to the last Max records when it holds twice as many.
+type History struct {
+	Path string
+	Max  int // records kept, 0 for DefaultHistoryMax, -1 for no limit
+
+	lines   int // records in the file, counted on the first Append
+	counted bool
+	mu      sync.Mutex
+}
+
+func NewHistory(path string) *History { return &History{Path: path} }
+
+// Append adds the record to the file.
+func (h *History) Append(r RunRecord) (err error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_ = os.MkdirAll(filepath.Dir(h.Path), 0o777)
+	f, err := os.OpenFile(h.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return
+	}
+	_, err = f.Write(append(data, '\n'))
+	if err = errors.Join(err, f.Close()); err != nil {
+		return
+	}
+
+	if !h.counted {
+		h.lines, _ = h.count()
+		h.counted = true
+	} else {
+		h.lines++
+	}
+	if limit := cmp.Or(h.Max, DefaultHistoryMax); limit > 0 && h.lines >= 2*limit {
+		if err = h.compact(limit); err == nil {
+			h.lines = limit
+		}
+	}
+	return
+}
+
+// count returns the number of the lines of the file, h.mu must be held.
+func (h *History) count() (n int, err error) {
+	err = h.scan(func([]byte) { n++ })
+	return
+}
+
+// compact rewrites the file with the last n lines, h.mu must be held.
+func (h *History) compact(n int) error {
+	var lines [][]byte
+	err := h.scan(func(line []byte) {
+		if lines = append(lines, slices.Clone(line)); len(lines) > n {
+			lines = lines[1:]
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp := h.Path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err = errors.Join(w.Flush(), f.Close()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, h.Path)
+}
+
+// Last returns the last n records, oldest first. n <= 0 returns all records.
+func (h *History) Last(n int) ([]RunRecord, error) {
+	return h.read(func(records []RunRecord) []RunRecord {
+		if n > 0 && len(records) > n {
+			records = records[1:]
+		}
+		return records
+	})
+}
+
+// Since returns the records started at or after t, oldest first.
+func (h *History) Since(t time.Time) ([]RunRecord, error) {
+	return h.read(func(records []RunRecord) []RunRecord {
+		if last := records[len(records)-1]; last.Start.Before(t) {
+			records = records[:len(records)-1]
+		}
+		return records
+	})
+}
+
+// read reads the records, keep is called after each appended record to trim the result.
+// Lines failed to decode, e.g. a partial line of a crash, are skipped.
+func (h *History) read(keep func([]RunRecord) []RunRecord) (records []RunRecord, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err = h.scan(func(line []byte) {
+		var r RunRecord
+		if json.Unmarshal(line, &r) == nil {
+			records = keep(append(records, r))
+		}
+	})
+	return
+}
+
+// scan calls fn with each line of the file, a missing file has no lines, h.mu must be held.
+func (h *History) scan(fn func(line []byte)) error {
+	f, err := os.Open(h.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	for scanner.Scan() {
+		fn(scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
+// tailLines keeps the last lines of the output.
+type tailLines struct {
+	lines   []string
+	max     int
+	writers []*lineWriter
+	mu      sync.Mutex
+}
+
+// Wrap returns a writer writes to w, and keeps the lines.
+func (t *tailLines) Wrap(w io.Writer) io.Writer {
+	lw := newLineWriter(t, "", "")
+	t.writers = append(t.writers, lw)
+	if w == nil {
+		return lw
+	}
+	return io.MultiWriter(w, lw)
+}
+
+func (t *tailLines) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.lines = append(t.lines, strings.TrimRight(string(p), "\r\n")); len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+	return len(p), nil
+}
+
+// Lines returns the last lines, including the pending line without newline.
+func (t *tailLines) Lines() []string {
+	for _, lw := range t.writers {
+		_ = lw.Close()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.lines...)
+}
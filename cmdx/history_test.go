@@ -0,0 +1,34 @@
+package cmdx
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryCompact(t *testing.T) {
+	h := NewHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	h.Max = 3
+
+	start := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	for i := range 7 {
+		if err := h.Append(RunRecord{Command: "sleep", Start: start.Add(time.Duration(i) * time.Minute), Reason: ReasonExit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Compacted to 3 at the 6th record, and the 7th appended.
+	all, err := h.Last(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := h.count(); len(all) != 4 || n != 4 || !all[0].Start.Equal(start.Add(3*time.Minute)) {
+		t.Errorf("records %d, lines %d, first %s", len(all), n, all[0].Start)
+	}
+
+	last, _ := h.Last(2)
+	since, _ := h.Since(start.Add(5 * time.Minute))
+	if len(last) != 2 || len(since) != 2 || !last[0].Start.Equal(since[0].Start) {
+		t.Errorf("last %v, since %v", last, since)
+	}
+}
@@ -0,0 +1,27 @@
+package cmdx
+
+// Isolation runs the program in new linux namespaces, optionally in a new root.
+// It needs root or CAP_SYS_ADMIN (or User for the namespaces), the program fails to
+// start with a clear error otherwise, there is no fallback to an isolated-less run.
+type Isolation struct {
+	// Root is the new root directory (chroot) of the program. With a root, Options.Dir is
+	// inside the root, default "/", and the executable must exist at the same path in it.
+	Root string `json:"root,omitempty" yaml:"root,omitempty"`
+
+	PID     bool `json:"pid,omitempty" yaml:"pid,omitempty"`         // new pid namespace, the program is pid 1
+	Mount   bool `json:"mount,omitempty" yaml:"mount,omitempty"`     // new mount namespace
+	UTS     bool `json:"uts,omitempty" yaml:"uts,omitempty"`         // new hostname namespace
+	Network bool `json:"network,omitempty" yaml:"network,omitempty"` // new network namespace, without any interface up
+	IPC     bool `json:"ipc,omitempty" yaml:"ipc,omitempty"`         // new ipc namespace
+	User    bool `json:"user,omitempty" yaml:"user,omitempty"`       // new user namespace, the current user is mapped to root
+
+	// Binds are bind mounted into Root before the program starts, and unmounted once it exited.
+	// The mounts are made by the supervisor, so they are visible outside the root while running.
+	Binds []Bind `json:"binds,omitempty" yaml:"binds,omitempty"`
+}
+
+type Bind struct {
+	Source   string `json:"source" yaml:"source"`                           // host path
+	Target   string `json:"target" yaml:"target"`                           // path inside Root
+	ReadOnly bool   `json:"read_only,omitempty" yaml:"read_only,omitempty"` // mount read-only
+}
@@ -0,0 +1,122 @@
+//go:build linux
+
+package cmdx
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"syscall"
+)
+
+// isolate sets up the namespaces and root of c, the binds are mounted in the mount namespace of
+// the supervisor, unbind undoes them and must be called once the process exited.
+func isolate(c *exec.Cmd, iso *Isolation) (unbind func(), err error) {
+	unbind = func() {}
+
+	if len(iso.Binds) > 0 && iso.Root == "" {
+		return unbind, errors.New("isolation: binds require a root")
+	}
+
+	attr := c.SysProcAttr
+	if attr == nil {
+		attr = &syscall.SysProcAttr{}
+		c.SysProcAttr = attr
+	}
+
+	for _, it := range []struct {
+		on   bool
+		flag uintptr
+	}{
+		{iso.PID, syscall.CLONE_NEWPID},
+		{iso.Mount || len(iso.Binds) > 0, syscall.CLONE_NEWNS},
+		{iso.UTS, syscall.CLONE_NEWUTS},
+		{iso.Network, syscall.CLONE_NEWNET},
+		{iso.IPC, syscall.CLONE_NEWIPC},
+		{iso.User, syscall.CLONE_NEWUSER},
+	} {
+		if it.on {
+			attr.Cloneflags |= it.flag
+		}
+	}
+
+	if iso.User {
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+		attr.GidMappingsEnableSetgroups = false
+	}
+
+	if iso.Root != "" {
+		if attr.Chroot, err = filepath.Abs(iso.Root); err != nil {
+			return unbind, fmt.Errorf("isolation: root: %w", err)
+		}
+		if c.Dir == "" || c.Dir == "." {
+			c.Dir = "/"
+		}
+	}
+
+	var mounted []string
+	unbind = func() {
+		for _, target := range slices.Backward(mounted) {
+			if err := syscall.Unmount(target, syscall.MNT_DETACH); err != nil {
+				slog.Warn("[cmdx] isolation unbind", "target", target, "err", err)
+			}
+		}
+		mounted = nil
+	}
+
+	for _, b := range iso.Binds {
+		target := filepath.Join(attr.Chroot, filepath.Clean("/"+b.Target))
+		if err = bindMount(b.Source, target, b.ReadOnly); err != nil {
+			unbind()
+			return unbind, fmt.Errorf("isolation: bind %s to %s: %w", b.Source, target, err)
+		}
+		mounted = append(mounted, target)
+	}
+	return
+}
+
+func bindMount(source, target string, readonly bool) (err error) {
+	stat, err := os.Stat(source)
+	if err != nil {
+		return
+	}
+
+	if stat.IsDir() {
+		err = os.MkdirAll(target, 0o755)
+	} else if err = os.MkdirAll(filepath.Dir(target), 0o755); err == nil {
+		var f *os.File
+		if f, err = os.OpenFile(target, os.O_CREATE, 0o644); err == nil {
+			err = f.Close()
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	if err = syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return
+	}
+
+	if readonly {
+		if err = syscall.Mount("", target, "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY, ""); err != nil {
+			_ = syscall.Unmount(target, syscall.MNT_DETACH)
+		}
+	}
+	return
+}
+
+// isolationError explains a start failure caused by missing capabilities or kernel support.
+func isolationError(err error) error {
+	switch {
+	case errors.Is(err, syscall.EPERM), errors.Is(err, syscall.ENOSPC):
+		return fmt.Errorf("isolation: namespaces or chroot not permitted, root, CAP_SYS_ADMIN or user namespaces are required: %w", err)
+	case errors.Is(err, syscall.EINVAL):
+		return fmt.Errorf("isolation: namespaces not supported by the kernel: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package cmdx
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsolationBinds(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("isolation requires root")
+	}
+
+	root, data := t.TempDir(), t.TempDir()
+	iso := &Isolation{Root: root, PID: true, Binds: []Bind{{Source: data, Target: "/data"}}}
+	// The system directories are bound read-only, or linked as on the host.
+	for _, dir := range []string{"/bin", "/lib", "/lib64", "/usr"} {
+		if link, err := os.Readlink(dir); err == nil {
+			os.Symlink(link, filepath.Join(root, dir))
+		} else if _, err := os.Stat(dir); err == nil {
+			iso.Binds = append(iso.Binds, Bind{Source: dir, Target: dir, ReadOnly: true})
+		}
+	}
+
+	s := Run(t.Context(), WithOptions(Options{Execute: "sh", Args: []string{"-c", "echo $$ > /data/pid; touch /usr/.x"}, Isolation: iso}))
+	if err := s.Wait(t.Context()); err == nil {
+		t.Error("wait = nil, want the write to the read-only /usr failed")
+	}
+
+	if pid, _ := os.ReadFile(filepath.Join(data, "pid")); strings.TrimSpace(string(pid)) != "1" {
+		t.Errorf("pid %q in the root, want 1", pid)
+	}
+
+	// The binds are unmounted once the run exited.
+	mounts, _ := os.ReadFile("/proc/self/mountinfo")
+	if strings.Contains(string(mounts), root) {
+		t.Errorf("binds in %s are still mounted", root)
+	}
+}
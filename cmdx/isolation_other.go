@@ -0,0 +1,16 @@
+//go:build !linux
+
+package cmdx
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+func isolate(*exec.Cmd, *Isolation) (unbind func(), err error) {
+	return func() {}, fmt.Errorf("isolation is not supported on %s: %w", runtime.GOOS, errors.ErrUnsupported)
+}
+
+func isolationError(err error) error { return err }
@@ -0,0 +1,73 @@
+package cmdx
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxLine limits the bytes buffered for a line without newline.
+const maxLine = 64 << 10
+
+// lineWriter writes whole lines to w, each prefixed by the time and the tag.
+type lineWriter struct {
+	w      io.Writer
+	layout string // timestamp layout, empty for no timestamp
+	tag    string
+
+	buf []byte
+	mu  sync.Mutex
+}
+
+func newLineWriter(w io.Writer, layout, tag string) *lineWriter {
+	return &lineWriter{w: w, layout: layout, tag: tag}
+}
+
+func (l *lineWriter) Write(p []byte) (n int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n = len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			if l.buf = append(l.buf, p...); len(l.buf) > maxLine {
+				err = l.flush(nil)
+			}
+			return
+		}
+
+		if err = l.flush(p[:i+1]); err != nil {
+			return
+		}
+		p = p[i+1:]
+	}
+	return
+}
+
+// Close writes the pending line without newline.
+func (l *lineWriter) Close() (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buf) > 0 {
+		err = l.flush([]byte{'\n'})
+	}
+	return
+}
+
+// flush writes the buffered part and the rest of the line, in a single write.
+func (l *lineWriter) flush(rest []byte) (err error) {
+	var line []byte
+	if l.layout != "" {
+		line = append(time.Now().AppendFormat(line, l.layout), ' ')
+	}
+	if l.tag != "" {
+		line = append(append(line, l.tag...), ' ')
+	}
+	line = append(append(line, l.buf...), rest...)
+	l.buf = l.buf[:0]
+
+	_, err = l.w.Write(line)
+	return
+}
@@ -0,0 +1,128 @@
+package cmdx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cnk3x/gox/strs"
+)
+
+// listenFdsStart is the first file descriptor passed by socket activation (SD_LISTEN_FDS_START).
+const listenFdsStart = 3
+
+// listener is a socket owned by the supervisor, it is kept open across the runs.
+type listener struct {
+	name string
+	file interface{ File() (*os.File, error) }
+	io.Closer
+}
+
+// listeners are the sockets declared by Options.Listen.
+type listeners []listener
+
+// listenAll opens the sockets, a socket is declared as
+//
+//	[tcp|tcp4|tcp6|udp|udp4|udp6|unix]://address[#name]
+//
+// e.g. ":8080", "tcp://127.0.0.1:8080#http", "unix:///run/app.sock#api".
+// The name is passed in LISTEN_FDNAMES, the scheme defaults to tcp.
+func listenAll(specs []string) (ls listeners, err error) {
+	for i, spec := range specs {
+		var l listener
+		if l, err = listen(spec); err != nil {
+			err = errors.Join(err, ls.Close())
+			return nil, err
+		}
+		if l.name == "" {
+			l.name = "fd" + strconv.Itoa(listenFdsStart+i)
+		}
+		ls = append(ls, l)
+	}
+	return
+}
+
+func listen(spec string) (l listener, err error) {
+	if !strings.Contains(spec, "://") {
+		spec = "tcp://" + spec
+	}
+
+	u, err := url.Parse(spec)
+	if err != nil {
+		return l, fmt.Errorf("listen %q: %w", spec, err)
+	}
+
+	l.name = u.Fragment
+	switch network := strs.Lower(u.Scheme); network {
+	case "tcp", "tcp4", "tcp6":
+		var addr *net.TCPAddr
+		if addr, err = net.ResolveTCPAddr(network, u.Host); err == nil {
+			var tl *net.TCPListener
+			if tl, err = net.ListenTCP(network, addr); err == nil {
+				l.file, l.Closer = tl, tl
+			}
+		}
+	case "udp", "udp4", "udp6":
+		var addr *net.UDPAddr
+		if addr, err = net.ResolveUDPAddr(network, u.Host); err == nil {
+			var uc *net.UDPConn
+			if uc, err = net.ListenUDP(network, addr); err == nil {
+				l.file, l.Closer = uc, uc
+			}
+		}
+	case "unix":
+		var ul *net.UnixListener
+		if ul, err = net.ListenUnix(network, &net.UnixAddr{Name: u.Host + u.Path, Net: network}); err == nil {
+			l.file, l.Closer = ul, ul
+		}
+	default:
+		err = fmt.Errorf("unsupported network %q", u.Scheme)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("listen %q: %w", spec, err)
+	}
+	return
+}
+
+// Files returns duplicated files of the sockets, for exec.Cmd.ExtraFiles.
+// They should be closed once the program is started.
+func (ls listeners) Files() (files []*os.File, err error) {
+	for _, l := range ls {
+		var f *os.File
+		if f, err = l.file.File(); err != nil {
+			closeFiles(files)
+			return nil, fmt.Errorf("listener %s: %w", l.name, err)
+		}
+		files = append(files, f)
+	}
+	return
+}
+
+// Env returns the socket activation environment, except LISTEN_PID which is set by setListenPid.
+func (ls listeners) Env() []string {
+	names := make([]string, len(ls))
+	for i, l := range ls {
+		names[i] = l.name
+	}
+	return []string{"LISTEN_FDS=" + strconv.Itoa(len(ls)), "LISTEN_FDNAMES=" + strings.Join(names, ":")}
+}
+
+func (ls listeners) Close() error {
+	var errs []error
+	for _, l := range ls {
+		errs = append(errs, l.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
@@ -0,0 +1,84 @@
+package cmdx
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestListen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	s := Run(t.Context(), WithOptions(Options{Execute: "sleep", Args: []string{"100s"}, Listen: []string{"unix://" + path + "#api"}, RestartMode: RestartStartFirst}))
+	defer s.Wait(t.Context())
+	defer s.Stop()
+
+	// check returns the socket inode of fd 3 of the run, after checking its environment.
+	check := func(run Generation) string {
+		<-run.Running
+		pid := s.Stat().Pid
+		// The environment is the one of sleep once the shell exec'd it.
+		waitFor(t, "LISTEN_PID", func() bool { return procEnv(pid, "LISTEN_PID") == strconv.Itoa(pid) })
+		for key, want := range map[string]string{"LISTEN_FDS": "1", "LISTEN_FDNAMES": "api"} {
+			if v := procEnv(pid, key); v != want {
+				t.Fatalf("%s = %q, want %q", key, v, want)
+			}
+		}
+		link, _ := os.Readlink("/proc/" + strconv.Itoa(pid) + "/fd/3")
+		inode, ok := strings.CutPrefix(link, "socket:[")
+		if !ok {
+			t.Fatalf("fd 3 = %q, want a socket", link)
+		}
+		inode = strings.TrimSuffix(inode, "]")
+		if p := unixSocketPath(inode); p != path {
+			t.Fatalf("fd 3 is bound to %q, want %q", p, path)
+		}
+		return inode
+	}
+
+	first := s.Current()
+	inode := check(first)
+	s.Restart()
+	<-first.Done
+	if again := check(s.Current()); again != inode {
+		t.Fatalf("socket after the restart = %s, want %s", again, inode)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+// procEnv returns the environment variable of the process.
+func procEnv(pid int, key string) string {
+	environ, _ := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/environ")
+	for entry := range bytes.SplitSeq(environ, []byte{0}) {
+		if v, ok := bytes.CutPrefix(entry, []byte(key+"=")); ok {
+			return string(v)
+		}
+	}
+	return ""
+}
+
+// unixSocketPath returns the path the unix socket of the inode is bound to.
+func unixSocketPath(inode string) string {
+	f, err := os.Open("/proc/net/unix")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Num RefCount Protocol Flags Type St Inode Path
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 8 && fields[6] == inode {
+			return fields[7]
+		}
+	}
+	return ""
+}
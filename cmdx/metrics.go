@@ -0,0 +1,129 @@
+package cmdx
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var metricStatuses = []Status{StatusUnknown, StatusStarting, StatusRunning, StatusRestarting, StatusStopping, StatusStopped}
+
+// Metrics is a http.Handler exports the registered programs in the prometheus text
+// exposition format.
+type Metrics struct {
+	programs map[string]*Result
+	mu       sync.RWMutex
+}
+
+func NewMetrics() *Metrics { return &Metrics{programs: map[string]*Result{}} }
+
+// Register adds the program by name, a registered name is replaced.
+func (m *Metrics) Register(name string, r *Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.programs[name] = r
+}
+
+func (m *Metrics) Unregister(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.programs, name)
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.RLock()
+	names := slices.Sorted(maps.Keys(m.programs))
+	programs := make([]Stat, len(names))
+	for i, name := range names {
+		programs[i] = m.programs[name].Stat()
+	}
+	m.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	now := time.Now()
+	metric := func(name, typ, help string, value func(name string, r Stat)) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for i, r := range programs {
+			value(names[i], r)
+		}
+	}
+	sample := func(metric, program string, value any, labels ...string) {
+		fmt.Fprintf(bw, "%s{program=\"%s\"", metric, escapeLabel(program))
+		for i := 0; i+1 < len(labels); i += 2 {
+			fmt.Fprintf(bw, ",%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		fmt.Fprintf(bw, "} %v\n", value)
+	}
+	boolean := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	metric("cmdx_program_status", "gauge", "Current status of the program, 1 for the current status.", func(name string, r Stat) {
+		for _, status := range metricStatuses {
+			sample("cmdx_program_status", name, boolean(r.Status == status), "status", status.String())
+		}
+	})
+
+	metric("cmdx_program_restarts_total", "counter", "Number of restarts of the program.", func(name string, r Stat) {
+		sample("cmdx_program_restarts_total", name, r.Restarts)
+	})
+
+	metric("cmdx_program_last_exit_code", "gauge", "Exit code of the last exited run, -1 if killed by a signal.", func(name string, r Stat) {
+		sample("cmdx_program_last_exit_code", name, r.LastExit)
+	})
+
+	metric("cmdx_program_start_timestamp_seconds", "gauge", "Start time of the current run since unix epoch in seconds.", func(name string, r Stat) {
+		sample("cmdx_program_start_timestamp_seconds", name, float64(r.StartTs)/1e9)
+	})
+
+	metric("cmdx_program_uptime_seconds", "gauge", "Time since the current run started in seconds, 0 if stopped.", func(name string, r Stat) {
+		var uptime float64
+		if r.StartTs > 0 && r.Status != StatusStopped {
+			uptime = Elapsed(time.Unix(0, r.StartTs), now).Seconds()
+		}
+		sample("cmdx_program_uptime_seconds", name, uptime)
+	})
+
+	type procStat struct {
+		cpu float64
+		rss int64
+	}
+
+	stats := make(map[string]procStat, len(programs))
+	for i, r := range programs {
+		if r.Status != StatusStopped {
+			if cpu, rss, ok := processStat(r.Pid); ok {
+				stats[names[i]] = procStat{cpu: cpu, rss: rss}
+			}
+		}
+	}
+
+	if len(stats) > 0 {
+		metric("cmdx_program_cpu_seconds_total", "counter", "User and system CPU time of the program process in seconds.", func(name string, r Stat) {
+			if st, ok := stats[name]; ok {
+				sample("cmdx_program_cpu_seconds_total", name, st.cpu)
+			}
+		})
+
+		metric("cmdx_program_resident_memory_bytes", "gauge", "Resident memory size of the program process in bytes.", func(name string, r Stat) {
+			if st, ok := stats[name]; ok {
+				sample("cmdx_program_resident_memory_bytes", name, st.rss)
+			}
+		})
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
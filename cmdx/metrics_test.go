@@ -0,0 +1,32 @@
+package cmdx
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	s := Run(t.Context(), WithOptions(Options{Execute: "sleep", Args: []string{"100s"}}))
+	m := NewMetrics()
+	m.Register("sleep", s)
+
+	// Scrape while the supervisor restarts the program.
+	time.AfterFunc(100*time.Millisecond, s.Restart)
+	var body string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if body = w.Body.String(); strings.Contains(body, `cmdx_program_restarts_total{program="sleep"} 1`) &&
+			strings.Contains(body, `cmdx_program_status{program="sleep",status="running"} 1`) {
+			break
+		}
+	}
+	s.Stop()
+	s.Wait(t.Context())
+
+	if !strings.Contains(body, `cmdx_program_restarts_total{program="sleep"} 1`) {
+		t.Errorf("metrics after restart:\n%s", body)
+	}
+}
@@ -0,0 +1,112 @@
+package cmdx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var notifySeq atomic.Uint64
+
+// notifySocket is the supervisor side of the systemd notify protocol (sd_notify),
+// a unix datagram socket passed to the program by NOTIFY_SOCKET.
+//
+// Like NotifyAccess=all, messages from any process are accepted.
+type notifySocket struct {
+	conn *net.UnixConn
+	path string
+}
+
+func listenNotify() (n *notifySocket, err error) {
+	n = &notifySocket{path: filepath.Join(os.TempDir(), fmt.Sprintf("cmdx-notify-%d-%d.sock", os.Getpid(), notifySeq.Add(1)))}
+	_ = os.Remove(n.path)
+	if n.conn, err = net.ListenUnixgram("unixgram", &net.UnixAddr{Name: n.path, Net: "unixgram"}); err != nil {
+		return nil, fmt.Errorf("listen notify socket: %w", err)
+	}
+	return
+}
+
+// Env returns the environment entries for the program.
+func (n *notifySocket) Env(watchdog time.Duration) (env []string) {
+	env = append(env, "NOTIFY_SOCKET="+n.path)
+	if watchdog > 0 {
+		env = append(env, "WATCHDOG_USEC="+strconv.FormatInt(watchdog.Microseconds(), 10))
+	}
+	return
+}
+
+func (n *notifySocket) Close() error {
+	err := n.conn.Close()
+	if e := os.Remove(n.path); e != nil && !errors.Is(e, os.ErrNotExist) {
+		err = errors.Join(err, e)
+	}
+	return err
+}
+
+// Serve handles the messages until the socket is closed.
+//
+// READY=1 calls ready and arms the watchdog, the program must then send WATCHDOG=1
+// within every watchdog interval, else, as for WATCHDOG=trigger, the run fails with
+// ErrWatchdog and is restarted. STATUS= is kept in Result.Message and MAINPID= in Result.Pid.
+func (n *notifySocket) Serve(s *Result, watchdog time.Duration, ready func(), fail func(err error, restart bool)) {
+	wd := time.AfterFunc(time.Hour, func() { fail(ErrWatchdog, true) })
+	wd.Stop()
+	defer wd.Stop()
+
+	var armed bool
+	buf := make([]byte, 4096)
+	for {
+		sz, err := n.conn.Read(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Debug("[cmdx] notify socket read", "err", err)
+			}
+			return
+		}
+
+		for line := range bytes.SplitSeq(buf[:sz], []byte{'\n'}) {
+			k, v, _ := bytes.Cut(line, []byte{'='})
+			switch string(k) {
+			case "READY":
+				if string(v) == "1" {
+					ready()
+					if armed = watchdog > 0; armed {
+						wd.Reset(watchdog)
+					}
+				}
+			case "WATCHDOG":
+				switch string(v) {
+				case "1":
+					if armed {
+						wd.Reset(watchdog)
+					}
+				case "trigger":
+					wd.Stop()
+					fail(ErrWatchdog, true)
+				}
+			case "WATCHDOG_USEC":
+				if usec, e := strconv.ParseInt(string(v), 10, 64); e == nil && usec > 0 {
+					if watchdog = time.Duration(usec) * time.Microsecond; armed {
+						wd.Reset(watchdog)
+					}
+				}
+			case "STATUS":
+				s.set(func() { s.Message = string(v) })
+			case "MAINPID":
+				if pid, e := strconv.Atoi(string(v)); e == nil && pid > 0 {
+					s.set(func() { s.Pid = pid })
+				}
+			case "":
+			default:
+				slog.Debug("[cmdx] notify", "command", s.Command, string(k), string(v))
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package cmdx
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	s := Run(t.Context(), WithOptions(Options{Execute: "sleep", Args: []string{"100s"}, Notify: true, Watchdog: 200 * time.Millisecond}))
+	defer s.Wait(t.Context())
+	defer s.Stop()
+
+	// The test sends the messages for the program, to the socket in its environment.
+	var path string
+	waitFor(t, "NOTIFY_SOCKET", func() bool { path = procEnv(s.Stat().Pid, "NOTIFY_SOCKET"); return path != "" })
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	send := func(msg string) {
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send("STATUS=warming up\nMAINPID=12345")
+	waitFor(t, "STATUS and MAINPID", func() bool { st := s.Stat(); return st.Message == "warming up" && st.Pid == 12345 })
+	if status := s.Stat().Status; status != StatusStarting {
+		t.Fatalf("status before READY = %s, want starting", status)
+	}
+
+	first := s.Current()
+	send("READY=1")
+	waitFor(t, "running", func() bool { return s.Stat().Status == StatusRunning })
+
+	// No WATCHDOG=1 within the interval, the program is restarted.
+	<-first.Done
+	// Stop is ignored while restarting, wait for the new run.
+	waitFor(t, "restart by the watchdog", func() bool { st := s.Stat(); return st.Restarts == 1 && st.Status == StatusStarting })
+}
@@ -0,0 +1,39 @@
+//go:build linux
+
+package cmdx
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+)
+
+// clockTicks is USER_HZ, which is 100 on all supported architectures.
+const clockTicks = 100
+
+// processStat returns the cpu time in seconds and the resident memory in bytes of the process.
+func processStat(pid int) (cpu float64, rss int64, ok bool) {
+	if pid <= 0 {
+		return
+	}
+
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return
+	}
+
+	// pid (comm) state ppid ... utime(14) stime(15) ... rss(24)
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return
+	}
+	fields := bytes.Fields(data[i+1:])
+	if len(fields) < 22 {
+		return
+	}
+
+	utime, _ := strconv.ParseUint(string(fields[11]), 10, 64)
+	stime, _ := strconv.ParseUint(string(fields[12]), 10, 64)
+	pages, _ := strconv.ParseInt(string(fields[21]), 10, 64)
+	return float64(utime+stime) / clockTicks, pages * int64(os.Getpagesize()), true
+}
@@ -0,0 +1,5 @@
+//go:build !linux
+
+package cmdx
+
+func processStat(int) (cpu float64, rss int64, ok bool) { return }
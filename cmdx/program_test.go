@@ -1,6 +1,7 @@
 package cmdx
 
 import (
+	"context"
 	"log/slog"
 	"testing"
 	"time"
@@ -26,5 +27,67 @@ loop:
 		}
 	}
 
-	s.Wait()
+	s.Wait(t.Context())
+}
+
+func TestReadyPattern(t *testing.T) {
+	s := Run(t.Context(), WithOptions(Options{
+		Execute:      "sh",
+		Args:         []string{"-c", "sleep 1; echo listening on :8080; sleep 100"},
+		ReadyPattern: `listening on :\d+`,
+		ReadyTimeout: 5 * time.Second,
+	}))
+
+	if status := s.Stat().Status; status != StatusStarting {
+		t.Fatalf("status = %s, want starting", status)
+	}
+
+	for code := range s.Changed {
+		if code == StatusRunning {
+			break
+		}
+	}
+	s.Stop()
+	s.Wait(t.Context())
+
+	s = Run(t.Context(), WithOptions(Options{Execute: "sleep", Args: []string{"100s"}, ReadyPattern: "ready", ReadyTimeout: time.Second}))
+	s.Wait(t.Context())
+	if err := s.Stat().Err; err != ErrUnready {
+		t.Fatalf("err = %v, want %v", err, ErrUnready)
+	}
+}
+
+func TestWaitGeneration(t *testing.T) {
+	s := Run(t.Context(), WithOptions(Options{Execute: "sleep", Args: []string{"100s"}}))
+
+	first := s.Current()
+	<-first.Running
+	s.Restart()
+	<-first.Done
+
+	if second := s.Current(); second.ID != first.ID+1 {
+		t.Fatalf("generation = %d, want %d", second.ID, first.ID+1)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	if err := s.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("wait = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	s.Stop()
+	<-s.Done()
+	if err := s.Wait(t.Context()); err != nil {
+		t.Fatalf("wait after stop = %v, want nil", err)
+	}
+}
+
+// waitFor polls cond until it is true, or fails the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+	}
 }
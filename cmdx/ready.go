@@ -0,0 +1,70 @@
+package cmdx
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sync"
+	"sync/atomic"
+)
+
+// readyMatcher calls onReady once, when a line written to any of its writers matches the pattern.
+type readyMatcher struct {
+	re      *regexp.Regexp
+	onReady func()
+	matched atomic.Bool
+}
+
+func newReadyMatcher(pattern string, onReady func()) (*readyMatcher, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &readyMatcher{re: re, onReady: sync.OnceFunc(onReady)}, nil
+}
+
+// Wrap returns a writer writes to w, and matches the lines.
+func (m *readyMatcher) Wrap(w io.Writer) io.Writer {
+	lw := &readyLineWriter{m: m}
+	if w == nil {
+		return lw
+	}
+	return io.MultiWriter(w, lw)
+}
+
+func (m *readyMatcher) match(line []byte) {
+	if m.re.Match(line) && m.matched.CompareAndSwap(false, true) {
+		m.onReady()
+	}
+}
+
+type readyLineWriter struct {
+	m   *readyMatcher
+	buf []byte
+	mu  sync.Mutex
+}
+
+func (w *readyLineWriter) Write(p []byte) (n int, err error) {
+	if n = len(p); w.m.matched.Load() {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.m.match(bytes.TrimRight(w.buf[:i], "\r"))
+		w.buf = w.buf[i+1:]
+	}
+
+	if len(w.buf) > maxLine {
+		w.m.match(w.buf)
+		w.buf = nil
+	}
+	return
+}
@@ -16,13 +16,29 @@ type RotateOptions struct {
 	Std        string `json:"std,omitempty" yaml:"std,omitempty"`                 // 标准输出
 	MaxSize    int64  `json:"max_size,omitempty" yaml:"max_size,omitempty"`       // 单文件最大大小
 	MaxBackups int    `json:"max_backups,omitempty" yaml:"max_backups,omitempty"` // 最大备份文件数量
+	Interval   string `json:"interval,omitempty" yaml:"interval,omitempty"`       // 按时间轮转: daily, hourly
+	Timestamp  bool   `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`     // 每行添加时间前缀
+	TimeLayout string `json:"time_layout,omitempty" yaml:"time_layout,omitempty"` // 时间前缀格式, 默认 DefaultTimeLayout
+	Sink       string `json:"sink,omitempty" yaml:"sink,omitempty"`               // 输出到 syslog://, tcp://, udp://, unix:// 或 RegisterSink 注册的地址
 }
 
+const (
+	IntervalDaily  = "daily"
+	IntervalHourly = "hourly"
+)
+
+const DefaultTimeLayout = "2006-01-02 15:04:05.000"
+
+// backupLayout names the backups, of the size and the period alike, so they sort by time.
+const backupLayout = "20060102-150405"
+
 func Rotate(options RotateOptions) io.WriteCloser {
 	w := &rotateWriter{
 		Path:       options.Path,
 		MaxSize:    options.MaxSize,
 		MaxBackups: options.MaxBackups,
+		Interval:   options.Interval,
+		now:        time.Now,
 	}
 	w.Init()
 	return w
@@ -32,9 +48,13 @@ type rotateWriter struct {
 	Path       string // 文件路径
 	MaxSize    int64  // 单文件最大大小
 	MaxBackups int    // 最大备份文件数量
+	Interval   string // 按时间轮转: daily, hourly
+
+	now func() time.Time
 
-	cur  *os.File
-	size atomic.Int64
+	cur    *os.File
+	period string // 当前文件所属的时间段
+	size   atomic.Int64
 
 	dir  string
 	name string
@@ -62,15 +82,21 @@ func (w *rotateWriter) Init() {
 }
 
 func (w *rotateWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if period := w.periodOf(w.now()); period != w.period {
+		if err = w.rotate(w.period); err != nil {
+			return
+		}
+	}
+
 	if n, err = w.cur.Write(p); err != nil {
 		return
 	}
 
-	if x := w.size.Add(int64(n)); x >= w.MaxSize {
-		w.mu.Lock()
-		err = w.rotate()
-		w.mu.Unlock()
-		if err != nil {
+	if x := w.size.Add(int64(n)); w.MaxSize > 0 && x >= w.MaxSize {
+		if err = w.rotate(""); err != nil {
 			return
 		}
 	} else if w.tr.CompareAndSwap(false, true) {
@@ -80,6 +106,8 @@ func (w *rotateWriter) Write(p []byte) (n int, err error) {
 }
 
 func (w *rotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.tt.Stop()
 	w.tr.Store(false)
 	return w.cur.Close()
@@ -89,20 +117,35 @@ func (w *rotateWriter) create() (err error) {
 	if w.cur, err = os.OpenFile(w.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666); err != nil {
 		return
 	}
-	if stat, _ := w.cur.Stat(); stat != nil {
+	if stat, _ := w.cur.Stat(); stat != nil && stat.Size() > 0 {
 		w.size.Store(stat.Size())
+		w.period = w.periodOf(stat.ModTime())
 	} else {
 		w.size.Store(0)
+		w.period = w.periodOf(w.now())
 	}
 	return
 }
 
-func (w *rotateWriter) backup() {
-	if err := w.gzBackup(w.Path+".backup", w.nameNow()+".gz", true); err == nil {
+// periodOf returns the time period of t, by the Interval.
+func (w *rotateWriter) periodOf(t time.Time) string {
+	switch w.Interval {
+	case IntervalDaily:
+		return t.Format("20060102")
+	case IntervalHourly:
+		return t.Format("2006010215")
+	default:
+		return ""
+	}
+}
+
+func (w *rotateWriter) backup(backupPath, name string) {
+	if err := w.gzBackup(backupPath, name+".gz", true); err == nil {
 		if w.MaxBackups > 0 {
 			files, _ := filepath.Glob(filepath.Join(w.dir, w.name+"*"+w.ext+".gz"))
 			if l := len(files); l > w.MaxBackups {
-				sort.Strings(files)
+				// newest first, keep the first MaxBackups
+				sort.Sort(sort.Reverse(sort.StringSlice(files)))
 				for i := w.MaxBackups; i < l; i++ {
 					os.Remove(files[i])
 				}
@@ -112,7 +155,8 @@ func (w *rotateWriter) backup() {
 	return
 }
 
-func (w *rotateWriter) rotate() (err error) {
+// rotate backups the current file, named by the period, or the time now if period is empty.
+func (w *rotateWriter) rotate(period string) (err error) {
 	if w.cur != nil {
 		_ = w.cur.Sync()
 
@@ -120,19 +164,39 @@ func (w *rotateWriter) rotate() (err error) {
 			return
 		}
 
-		backupPath := w.Path + ".backup"
+		name := w.backupName(w.now())
+		if period != "" {
+			// The last second of the period sorts after the size backups of the period.
+			if byPeriod := w.backupName(w.periodEnd(period)); !exists(byPeriod + ".gz") {
+				name = byPeriod
+			}
+		}
+
+		backupPath := name + ".backup"
 		if err = os.Rename(w.Path, backupPath); err != nil {
 			return
 		}
 
-		go w.backup()
+		go w.backup(backupPath, name)
 	}
 
 	return w.create()
 }
 
-func (w *rotateWriter) nameNow() string {
-	return filepath.Join(w.dir, w.name+"-"+time.Now().Format("20060102-150405")+w.ext)
+func (w *rotateWriter) backupName(t time.Time) string {
+	return filepath.Join(w.dir, w.name+"-"+t.Format(backupLayout)+w.ext)
+}
+
+// periodEnd returns the last second of the period.
+func (w *rotateWriter) periodEnd(period string) time.Time {
+	switch w.Interval {
+	case IntervalDaily:
+		t, _ := time.ParseInLocation("20060102", period, time.Local)
+		return t.AddDate(0, 0, 1).Add(-time.Second)
+	default:
+		t, _ := time.ParseInLocation("2006010215", period, time.Local)
+		return t.Add(time.Hour - time.Second)
+	}
 }
 
 func (w *rotateWriter) gzBackup(sourcePath string, targetPath string, removeSource bool) (err error) {
@@ -177,3 +241,8 @@ func (w *rotateWriter) closeIt(c io.Closer, err error) error {
 	}
 	return err
 }
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,85 @@
+package cmdx
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateInterval(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, time.October, 19, 15, 10, 0, 0, time.Local)
+	w := &rotateWriter{Path: filepath.Join(dir, "app.log"), MaxSize: 10, MaxBackups: 2, Interval: IntervalHourly, now: func() time.Time { return now }}
+	w.Init()
+	defer w.Close()
+
+	for _, step := range []struct {
+		at   string
+		data string
+	}{
+		{"15:10:00", "0123456789"}, // size backup
+		{"15:20:00", "abc"},
+		{"16:05:00", "x"},          // hourly backup of 15:20 and the 16:05 in the new file
+		{"16:06:00", "0123456789"}, // size backup
+	} {
+		at, _ := time.ParseInLocation(time.TimeOnly, step.at, time.Local)
+		now = time.Date(2026, time.October, 19, at.Hour(), at.Minute(), at.Second(), 0, time.Local)
+		if _, err := w.Write([]byte(step.data)); err != nil {
+			t.Fatal(err)
+		}
+		waitBackups(t, dir)
+	}
+
+	// The pruning follows the compression.
+	var files []string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if files, _ = filepath.Glob(filepath.Join(dir, "*.gz")); len(files) <= 2 {
+			break
+		}
+	}
+	for i, f := range files {
+		files[i] = filepath.Base(f)
+	}
+	if want := []string{"app-20261019-155959.log.gz", "app-20261019-160600.log.gz"}; !slices.Equal(files, want) {
+		t.Errorf("backups %v, want %v", files, want)
+	}
+}
+
+func TestMergeTimestamp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	options := &RotateOptions{Path: path, Timestamp: true}
+
+	f := createLoggerFactory()
+	stdout, stderr := f.CreateTag("[out]", options), f.CreateTag("[err]", options)
+	stdout.Write([]byte("a\nb"))
+	stderr.Write([]byte("e\n"))
+	stdout.Write([]byte("c\nd"))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	want := []string{"[out] a", "[err] e", "[out] bc", "[out] d"}
+	stamp := regexp.MustCompile(`^\d{4}-\d\d-\d\d \d\d:\d\d:\d\d\.\d{3} `)
+	for i, line := range lines {
+		if !stamp.MatchString(line) || i >= len(want) || stamp.ReplaceAllString(line, "") != want[i] {
+			t.Errorf("lines %q, want %q with timestamps", lines, want)
+			break
+		}
+	}
+}
+
+// waitBackups waits until the backups in dir are compressed.
+func waitBackups(t *testing.T, dir string) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if pending, _ := filepath.Glob(filepath.Join(dir, "*.backup")); len(pending) == 0 {
+			return
+		}
+	}
+	t.Fatal("backups are not compressed")
+}
@@ -0,0 +1,100 @@
+package cmdx
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/cnk3x/gox/cron"
+	"github.com/cnk3x/gox/strs"
+)
+
+// Schedule actions.
+const (
+	ActionRestart = "restart" // restart the program
+	ActionStop    = "stop"    // stop the program
+	ActionStart   = "start"   // start the program if stopped
+	ActionSignal  = "signal"  // send Schedule.Signal to the program
+	ActionExec    = "exec"    // run Schedule.Exec once, e.g. a maintenance script
+)
+
+// scheduleStopTimeout is the time to wait for the running actions when the program is done.
+const scheduleStopTimeout = 10 * time.Second
+
+// Schedule is an action of the program run on a cron spec.
+type Schedule struct {
+	Spec   string   `json:"spec" yaml:"spec"`                         // cron spec, e.g. "0 30 3 * * *", "@every 1h"
+	Action string   `json:"action" yaml:"action"`                     // restart, stop, start, signal, exec
+	Signal string   `json:"signal,omitempty" yaml:"signal,omitempty"` // signal of the signal action, e.g. HUP, USR1
+	Exec   *Options `json:"exec,omitempty" yaml:"exec,omitempty"`     // one-shot command of the exec action, dir defaults to the program dir
+}
+
+// startSchedules schedules the actions on s, until stop is called.
+func startSchedules(s *Result, options Options) (stop func(), err error) {
+	c := cron.New()
+	for i, sch := range options.Schedules {
+		var action cron.Job
+		if action, err = scheduleAction(s, options, sch); err != nil {
+			return nil, fmt.Errorf("schedule #%d %q: %w", i, sch.Spec, err)
+		}
+
+		name := options.Execute + "#" + strconv.Itoa(i) + ":" + sch.Action
+		if _, err = c.Add(name, sch.Spec, action); err != nil {
+			return nil, fmt.Errorf("schedule #%d %q: %w", i, sch.Spec, err)
+		}
+	}
+
+	go c.Run()
+	stop = func() {
+		ctx, cancel := context.WithTimeout(context.Background(), scheduleStopTimeout)
+		defer cancel()
+		c.Stop(ctx)
+	}
+	return stop, nil
+}
+
+// scheduleAction returns the job of the action, an exec action runs with the job context,
+// so it is canceled when the schedules are stopped.
+func scheduleAction(s *Result, options Options, sch Schedule) (action cron.Job, err error) {
+	switch strs.Lower(sch.Action) {
+	case ActionRestart:
+		action = cron.Func(s.Restart)
+	case ActionStop:
+		action = cron.Func(s.Stop)
+	case ActionStart:
+		action = cron.Func(s.Start)
+	case ActionSignal:
+		if sch.Signal == "" {
+			return nil, fmt.Errorf("missing signal")
+		}
+		action = func(context.Context) error {
+			st := s.Stat()
+			if st.Status != StatusRunning {
+				return nil
+			}
+			if err := signalProcess(st.Pid, sch.Signal); err != nil {
+				slog.Warn("[cmdx] schedule signal", "command", s.Command, "signal", sch.Signal, "err", err)
+				return err
+			}
+			return nil
+		}
+	case ActionExec:
+		if sch.Exec == nil || sch.Exec.Execute == "" {
+			return nil, fmt.Errorf("missing exec command")
+		}
+		once := *sch.Exec
+		once.Dir = cmp.Or(once.Dir, options.Dir)
+		action = func(ctx context.Context) error {
+			r := Run(ctx, WithOptions(once))
+			err := r.Wait(ctx)
+			slog.Info("[cmdx] schedule exec", "command", r.Command, "exit", r.Stat().Exit, "err", err)
+			return err
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", sch.Action)
+	}
+	return
+}
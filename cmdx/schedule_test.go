@@ -0,0 +1,39 @@
+package cmdx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScheduleRestart(t *testing.T) {
+	s := Run(t.Context(), WithOptions(Options{Execute: "sleep", Args: []string{"100s"}, Schedules: []Schedule{{Spec: "* * * * * *", Action: ActionRestart}}}))
+	waitFor(t, "a scheduled restart", func() bool { st := s.Stat(); return st.Restarts > 0 && st.Status == StatusRunning })
+	s.Stop()
+	s.Wait(t.Context())
+}
+
+func TestScheduleStop(t *testing.T) {
+	s := Run(t.Context(), WithOptions(Options{Execute: "sleep", Args: []string{"100s"}, Schedules: []Schedule{{Spec: "* * * * * *", Action: ActionStop}}}))
+	waitFor(t, "a scheduled stop", func() bool {
+		select {
+		case <-s.Done():
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+func TestScheduleSignal(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hup")
+	s := Run(t.Context(), WithOptions(Options{
+		Execute:   "sh",
+		Args:      []string{"-c", `trap 'echo hup > "$0"' HUP; while :; do sleep 0.1; done`, out},
+		Schedules: []Schedule{{Spec: "* * * * * *", Action: ActionSignal, Signal: "HUP"}},
+	}))
+	defer s.Wait(t.Context())
+	defer s.Stop()
+
+	waitFor(t, "the scheduled signal", func() bool { _, err := os.Stat(out); return err == nil })
+}
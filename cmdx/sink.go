@@ -0,0 +1,213 @@
+package cmdx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cnk3x/gox/strs"
+)
+
+// Sink is an output destination of the program lines, such as a log collector.
+type Sink interface {
+	// Open connects to the destination, it is called again to reconnect after a write error.
+	Open() (io.WriteCloser, error)
+}
+
+// SinkFunc is a function implements Sink.
+type SinkFunc func() (io.WriteCloser, error)
+
+func (f SinkFunc) Open() (io.WriteCloser, error) { return f() }
+
+// SinkCreator creates a sink from the url, see RegisterSink.
+type SinkCreator func(u *url.URL) (Sink, error)
+
+var (
+	sinks   = map[string]SinkCreator{}
+	sinksMu sync.RWMutex
+)
+
+// RegisterSink registers a sink for the url scheme used in RotateOptions.Sink.
+//
+// Builtin schemes are tcp, udp, unix (e.g. "tcp://host:port", "unix:///run/log.sock")
+// and syslog (e.g. "syslog://", "syslog://host:514?tag=app&facility=local0").
+func RegisterSink(scheme string, create SinkCreator) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	sinks[strs.Lower(scheme)] = create
+}
+
+func init() {
+	for _, network := range []string{"tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "unix", "unixgram"} {
+		RegisterSink(network, dialSink)
+	}
+}
+
+func dialSink(u *url.URL) (Sink, error) {
+	network, address := strs.Lower(u.Scheme), u.Host
+	if network == "unix" || network == "unixgram" {
+		address = u.Host + u.Path
+	}
+	if address == "" {
+		return nil, fmt.Errorf("sink %s: missing address", u.Redacted())
+	}
+	return SinkFunc(func() (io.WriteCloser, error) { return net.DialTimeout(network, address, 5*time.Second) }), nil
+}
+
+// OpenSink creates a writer to the sink of the url. Lines are buffered and written in
+// background, reconnecting the sink on error. Lines are dropped while the buffer is full.
+func OpenSink(rawURL string) (io.WriteCloser, error) {
+	sink, name, err := createSink(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	sw := &sinkWriter{name: name, sink: sink, queue: make(chan []byte, 1024), stop: make(chan struct{}), done: make(chan struct{})}
+	go sw.loop()
+	return &sinkLines{lineWriter: newLineWriter(sw, "", ""), sw: sw}, nil
+}
+
+// createSink creates the sink of the url, it is not connected until opened.
+func createSink(rawURL string) (sink Sink, name string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("sink %q: %w", rawURL, err)
+	}
+
+	sinksMu.RLock()
+	create := sinks[strs.Lower(u.Scheme)]
+	sinksMu.RUnlock()
+	if create == nil {
+		return nil, "", fmt.Errorf("sink %s: unknown scheme %q", u.Redacted(), u.Scheme)
+	}
+
+	if sink, err = create(u); err != nil {
+		return nil, "", err
+	}
+	return sink, u.Redacted(), nil
+}
+
+// checkSinks creates the sinks of the logger, so a bad sink fails the run before it starts.
+func checkSinks(l *Logger) error {
+	if l == nil {
+		return nil
+	}
+	for _, it := range []*RotateOptions{l.RotateOptions, l.Stdout, l.Stderr} {
+		if it != nil && strs.TrimSpace(it.Sink) != "" {
+			if _, _, err := createSink(strs.TrimSpace(it.Sink)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sinkLines splits the output into lines, so each line is a message of the sink.
+type sinkLines struct {
+	*lineWriter
+	sw *sinkWriter
+}
+
+func (s *sinkLines) Close() error {
+	_ = s.lineWriter.Close()
+	return s.sw.Close()
+}
+
+// sinkWriter writes to the sink in background.
+type sinkWriter struct {
+	name  string
+	sink  Sink
+	queue chan []byte
+	stop  chan struct{} // closed if the queue can not be drained in time on close
+	done  chan struct{}
+
+	closed  bool
+	dropped atomic.Int64
+	mu      sync.RWMutex
+}
+
+func (w *sinkWriter) Write(p []byte) (n int, err error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	select {
+	case w.queue <- bytes.Clone(p):
+	default:
+		if w.dropped.Add(1) == 1 {
+			slog.Warn("[cmdx] sink buffer full, dropping lines", "sink", w.name)
+		}
+	}
+	return len(p), nil
+}
+
+// Close flushes the buffered lines, waits 5 seconds at most.
+func (w *sinkWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+	case <-time.After(5 * time.Second):
+		close(w.stop)
+		<-w.done
+	}
+
+	if n := w.dropped.Load(); n > 0 {
+		slog.Warn("[cmdx] sink dropped lines", "sink", w.name, "dropped", n)
+	}
+	return nil
+}
+
+func (w *sinkWriter) loop() {
+	defer close(w.done)
+
+	var conn io.WriteCloser
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	backoff := time.Duration(0)
+	for p := range w.queue {
+		for {
+			var err error
+			if conn == nil {
+				if conn, err = w.sink.Open(); err != nil {
+					conn = nil
+				}
+			}
+
+			if conn != nil {
+				if _, err = conn.Write(p); err == nil {
+					backoff = 0
+					break
+				}
+				_ = conn.Close()
+				conn = nil
+			}
+
+			backoff = min(max(backoff*2, 100*time.Millisecond), 10*time.Second)
+			slog.Debug("[cmdx] sink reconnect", "sink", w.name, "err", err, "after", backoff)
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(backoff):
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+//go:build !windows && !plan9
+
+package cmdx
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/cnk3x/gox/strs"
+)
+
+func init() { RegisterSink("syslog", syslogSink) }
+
+var (
+	syslogFacilities = map[string]syslog.Priority{
+		"kern": syslog.LOG_KERN, "user": syslog.LOG_USER, "mail": syslog.LOG_MAIL, "daemon": syslog.LOG_DAEMON,
+		"auth": syslog.LOG_AUTH, "syslog": syslog.LOG_SYSLOG, "lpr": syslog.LOG_LPR, "news": syslog.LOG_NEWS,
+		"uucp": syslog.LOG_UUCP, "cron": syslog.LOG_CRON, "authpriv": syslog.LOG_AUTHPRIV, "ftp": syslog.LOG_FTP,
+		"local0": syslog.LOG_LOCAL0, "local1": syslog.LOG_LOCAL1, "local2": syslog.LOG_LOCAL2, "local3": syslog.LOG_LOCAL3,
+		"local4": syslog.LOG_LOCAL4, "local5": syslog.LOG_LOCAL5, "local6": syslog.LOG_LOCAL6, "local7": syslog.LOG_LOCAL7,
+	}
+
+	syslogSeverities = map[string]syslog.Priority{
+		"emerg": syslog.LOG_EMERG, "alert": syslog.LOG_ALERT, "crit": syslog.LOG_CRIT, "err": syslog.LOG_ERR,
+		"warning": syslog.LOG_WARNING, "notice": syslog.LOG_NOTICE, "info": syslog.LOG_INFO, "debug": syslog.LOG_DEBUG,
+	}
+)
+
+// syslogSink creates a syslog sink, the local syslog if the host is empty.
+//
+//	syslog://[host:port][?network=udp|tcp&tag=name&facility=daemon&severity=info]
+func syslogSink(u *url.URL) (Sink, error) {
+	q := u.Query()
+
+	network := strs.Lower(q.Get("network"))
+	if network == "" && u.Host != "" {
+		network = "udp"
+	}
+
+	facility, ok := syslogFacilities[strs.Lower(cmp.Or(q.Get("facility"), "daemon"))]
+	if !ok {
+		return nil, fmt.Errorf("sink %s: unknown facility %q", u.Redacted(), q.Get("facility"))
+	}
+
+	severity, ok := syslogSeverities[strs.Lower(cmp.Or(q.Get("severity"), "info"))]
+	if !ok {
+		return nil, fmt.Errorf("sink %s: unknown severity %q", u.Redacted(), q.Get("severity"))
+	}
+
+	tag := cmp.Or(q.Get("tag"), filepath.Base(os.Args[0]))
+	return SinkFunc(func() (io.WriteCloser, error) { return syslog.Dial(network, u.Host, facility|severity, tag) }), nil
+}
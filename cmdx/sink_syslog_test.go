@@ -0,0 +1,36 @@
+//go:build !windows && !plan9
+
+package cmdx
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSink(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	w, err := OpenSink("syslog://" + pc.LocalAddr().String() + "?tag=app&facility=local0&severity=notice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write([]byte("hello\n"))
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// local0.notice is 16*8+5.
+	if msg := string(buf[:n]); !strings.HasPrefix(msg, "<133>") || !strings.Contains(msg, " app[") || !strings.HasSuffix(msg, "hello\n") {
+		t.Errorf("message %q, want <133> from app with hello", msg)
+	}
+}
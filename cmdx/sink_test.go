@@ -0,0 +1,113 @@
+package cmdx
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSinkReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	w, err := OpenSink("tcp://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// The lines are buffered while the collector is down.
+	w.Write([]byte("a\nb\n"))
+	time.Sleep(200 * time.Millisecond)
+
+	serve := func() (*bufio.Scanner, func()) {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+		conn, err := l.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return bufio.NewScanner(conn), func() { conn.Close(); l.Close() }
+	}
+
+	lines, stop := serve()
+	for _, want := range []string{"a", "b"} {
+		if !lines.Scan() || lines.Text() != want {
+			t.Fatalf("line %q, want %q", lines.Text(), want)
+		}
+	}
+	stop()
+
+	// The sink reconnects once the collector is back, a line written to the broken
+	// connection may be lost, so the test writes until one arrives.
+	stopWriting := make(chan struct{})
+	defer close(stopWriting)
+	go func() {
+		for {
+			select {
+			case <-stopWriting:
+				return
+			case <-time.After(50 * time.Millisecond):
+				w.Write([]byte("c\n"))
+			}
+		}
+	}()
+	lines, stop = serve()
+	defer stop()
+	if !lines.Scan() || lines.Text() != "c" {
+		t.Fatalf("line %q after reconnect, want %q", lines.Text(), "c")
+	}
+}
+
+func TestSinkDropped(t *testing.T) {
+	opened, release := make(chan struct{}), make(chan struct{})
+	RegisterSink("blocked", func(*url.URL) (Sink, error) {
+		return SinkFunc(func() (io.WriteCloser, error) {
+			close(opened)
+			<-release
+			return nopCloser{io.Discard}, nil
+		}), nil
+	})
+
+	w, err := OpenSink("blocked://")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first line is taken by the blocked sink, the buffer holds 1024 more.
+	w.Write([]byte("0\n"))
+	<-opened
+	for range 1024 + 5 {
+		w.Write([]byte("x\n"))
+	}
+	if n := w.(*sinkLines).sw.dropped.Load(); n != 5 {
+		t.Errorf("dropped %d, want 5", n)
+	}
+
+	close(release)
+	w.Close()
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestSinkInvalid(t *testing.T) {
+	for _, sink := range []string{"nope://host", "tcp://", "syslog://?facility=nope"} {
+		s := Run(t.Context(), WithOptions(Options{Execute: "true", Logger: &Logger{RotateOptions: &RotateOptions{Sink: sink}}}))
+		if err := s.Wait(t.Context()); err == nil {
+			t.Errorf("sink %s: wait = nil, want an error", sink)
+		}
+	}
+}
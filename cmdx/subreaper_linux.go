@@ -0,0 +1,291 @@
+//go:build linux
+
+package cmdx
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const prSetChildSubreaper = 36 // PR_SET_CHILD_SUBREAPER
+
+// setSubreaper marks the current process as a child subreaper, so orphaned
+// descendants of supervised programs are re-parented to us instead of init.
+// It is process wide and only done once.
+var setSubreaper = sync.OnceValue(func() error {
+	if _, _, e := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); e != 0 {
+		return os.NewSyscallError("prctl(PR_SET_CHILD_SUBREAPER)", e)
+	}
+	go reapLoop()
+	slog.Debug("[cmdx] child subreaper enabled")
+	return nil
+})
+
+// markerEnv is set in the environment of programs with subreaper enabled, it
+// attributes the orphans re-parented to us whose ancestry is already gone.
+const markerEnv = "CMDX_SUBREAPER"
+
+var (
+	trackers   = map[*descendants]struct{}{}
+	trackersMu sync.Mutex
+	trackerSeq atomic.Uint64
+
+	roots   = map[int]struct{}{} // pids of the started programs, left to exec.Cmd.Wait
+	rootsMu sync.RWMutex         // read locked while starting, so a root is never reaped by reapOrphans
+)
+
+// startRoot starts c, the reaper leaves its pid to c.Wait until waited is called.
+func startRoot(c *exec.Cmd) (waited func(), err error) {
+	rootsMu.RLock()
+	defer rootsMu.RUnlock()
+	if err = c.Start(); err != nil {
+		return
+	}
+
+	pid := c.Process.Pid
+	trackersMu.Lock()
+	roots[pid] = struct{}{}
+	trackersMu.Unlock()
+	return func() {
+		trackersMu.Lock()
+		delete(roots, pid)
+		trackersMu.Unlock()
+	}, nil
+}
+
+// reapLoop waits for the orphans re-parented to us, on SIGCHLD and periodically.
+// The subreaper is process wide, so the orphans of every program are reaped, not only
+// the tracked ones.
+func reapLoop() {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGCHLD)
+	tick := time.NewTicker(5 * time.Second)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-sigc:
+		case <-tick.C:
+		}
+
+		reapOrphans()
+
+		trackersMu.Lock()
+		for d := range trackers {
+			if d.prune() == 0 && d.stopped() {
+				delete(trackers, d)
+			}
+		}
+		trackersMu.Unlock()
+	}
+}
+
+// reapOrphans waits for the exited children re-parented to us. The program roots are left
+// to exec.Cmd.Wait, and so are the children in our own process group, e.g. the commands
+// of the host started without cmdx.
+func reapOrphans() {
+	rootsMu.Lock()
+	defer rootsMu.Unlock()
+
+	self, group := os.Getpid(), syscall.Getpgrp()
+	for pid, p := range readProcs() {
+		if p.ppid != self || p.state != 'Z' || p.pgrp == group {
+			continue
+		}
+		trackersMu.Lock()
+		_, root := roots[pid]
+		trackersMu.Unlock()
+		if root {
+			continue
+		}
+
+		var ws syscall.WaitStatus
+		if wpid, _ := syscall.Wait4(pid, &ws, syscall.WNOHANG, nil); wpid == pid {
+			slog.Debug("[cmdx] reaped orphan", "pid", pid, "exit", ws.ExitStatus())
+		}
+	}
+}
+
+// descendants tracks all processes started by a supervised program, by process group
+// and by /proc ancestry. The root pid itself is left to exec.Cmd.Wait.
+type descendants struct {
+	root   int
+	marker string
+	known  map[int]struct{}
+	stop   chan struct{}
+	mu     sync.Mutex
+}
+
+func newDescendants() *descendants {
+	return &descendants{
+		marker: markerEnv + "=" + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatUint(trackerSeq.Add(1), 10),
+		known:  map[int]struct{}{},
+		stop:   make(chan struct{}),
+	}
+}
+
+// env returns the marker to add to the program environment.
+func (d *descendants) env() string { return d.marker }
+
+// track starts tracking the descendants of root.
+func (d *descendants) track(root int) {
+	if d == nil {
+		return
+	}
+
+	d.root = root
+	trackersMu.Lock()
+	trackers[d] = struct{}{}
+	trackersMu.Unlock()
+
+	go func() {
+		tick := time.NewTicker(500 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			d.scan()
+			select {
+			case <-d.stop:
+				return
+			case <-tick.C:
+			}
+		}
+	}()
+}
+
+// scan refreshes the known descendants from /proc.
+// A process belongs to the program if it is in the program's process group,
+// or its parent is the root or an already known descendant, or it is an orphan
+// re-parented to us carrying the marker in its environment.
+func (d *descendants) scan() {
+	procs, self := readProcs(), os.Getpid()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for pid := range d.known {
+		if _, alive := procs[pid]; !alive {
+			delete(d.known, pid)
+		}
+	}
+
+	for added := true; added; {
+		added = false
+		for pid, p := range procs {
+			if _, found := d.known[pid]; found || pid == d.root {
+				continue
+			}
+			_, parentKnown := d.known[p.ppid]
+			if p.pgrp == d.root || p.ppid == d.root || parentKnown || (p.ppid == self && hasEnv(pid, d.marker)) {
+				d.known[pid] = struct{}{}
+				added = true
+			}
+		}
+	}
+}
+
+// prune forgets the known descendants gone from /proc, it returns the number of
+// descendants still known.
+func (d *descendants) prune() (remain int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for pid := range d.known {
+		if _, e := os.Stat("/proc/" + strconv.Itoa(pid)); os.IsNotExist(e) {
+			delete(d.known, pid)
+		}
+	}
+	return len(d.known)
+}
+
+func (d *descendants) stopped() bool {
+	select {
+	case <-d.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// terminate sends SIGTERM to all known descendants.
+func (d *descendants) terminate() {
+	if d == nil {
+		return
+	}
+	d.scan()
+	d.signal(syscall.SIGTERM)
+}
+
+// kill stops tracking and kills any descendant that is still alive.
+func (d *descendants) kill() {
+	if d == nil {
+		return
+	}
+
+	close(d.stop)
+	d.scan()
+	d.signal(syscall.SIGKILL)
+}
+
+func (d *descendants) signal(sig syscall.Signal) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for pid := range d.known {
+		if err := syscall.Kill(pid, sig); err == nil {
+			slog.Debug("[cmdx] signal orphan", "pid", pid, "root", d.root, "signal", sig)
+		}
+	}
+}
+
+type procInfo struct {
+	state      byte
+	ppid, pgrp int
+}
+
+// readProcs reads the state, parent pid and process group of all processes from /proc.
+func readProcs() map[int]procInfo {
+	procs := map[int]procInfo{}
+	entries, _ := os.ReadDir("/proc")
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		data, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+
+		// pid (comm) state ppid pgrp ...; comm may contain spaces and parentheses.
+		if i := bytes.LastIndexByte(data, ')'); i > 0 {
+			if fields := bytes.Fields(data[i+1:]); len(fields) > 2 {
+				ppid, _ := strconv.Atoi(string(fields[1]))
+				pgrp, _ := strconv.Atoi(string(fields[2]))
+				procs[pid] = procInfo{state: fields[0][0], ppid: ppid, pgrp: pgrp}
+			}
+		}
+	}
+	return procs
+}
+
+// hasEnv reports whether the process environment contains the entry.
+func hasEnv(pid int, entry string) bool {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/environ")
+	if err != nil {
+		return false
+	}
+	for item := range bytes.SplitSeq(data, []byte{0}) {
+		if string(item) == entry {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package cmdx
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSubreaperOrphans(t *testing.T) {
+	reaper := Run(t.Context(), WithOptions(Options{Execute: "sleep", Args: []string{"100s"}, Subreaper: true}))
+	defer reaper.Wait(t.Context())
+	defer reaper.Stop()
+
+	// The orphan of a program without Subreaper is re-parented to us as well.
+	plain := Run(t.Context(), WithOptions(Options{Execute: "sh", Args: []string{"-c", "(sleep 1 &); sleep 100"}}))
+	defer plain.Wait(t.Context())
+	defer plain.Stop()
+
+	self := os.Getpid()
+	orphan := false
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		zombies := 0
+		for _, p := range readProcs() {
+			if p.ppid == self {
+				orphan = orphan || p.pgrp == plain.Stat().Pid
+				if p.state == 'Z' {
+					zombies++
+				}
+			}
+		}
+		if orphan && zombies == 0 && time.Until(deadline) < 3*time.Second {
+			return
+		}
+	}
+	t.Errorf("orphan re-parented %v, zombies left", orphan)
+}
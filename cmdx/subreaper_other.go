@@ -0,0 +1,26 @@
+//go:build !linux
+
+package cmdx
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+func setSubreaper() error {
+	return fmt.Errorf("child subreaper is not supported on %s: %w", runtime.GOOS, errors.ErrUnsupported)
+}
+
+// startRoot starts c, there is no reaper to keep it from.
+func startRoot(c *exec.Cmd) (waited func(), err error) { return func() {}, c.Start() }
+
+type descendants struct{}
+
+func newDescendants() *descendants { return nil }
+
+func (*descendants) env() string { return "" }
+func (*descendants) track(int)   {}
+func (*descendants) terminate()  {}
+func (*descendants) kill()       {}
@@ -0,0 +1,144 @@
+package cron
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cnk3x/gox/strs"
+)
+
+// Calendar is a set of dates, e.g. the public holidays, see Except. It is not safe to add
+// dates while it is in use by the schedules.
+type Calendar struct {
+	dates  map[date]struct{}
+	yearly map[date]struct{} // the month and day of every year, Year is 0
+}
+
+type date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// NewCalendar returns a calendar of the dates of the times.
+func NewCalendar(times ...time.Time) *Calendar {
+	cal := &Calendar{dates: map[date]struct{}{}, yearly: map[date]struct{}{}}
+	for _, t := range times {
+		cal.Add(t)
+	}
+	return cal
+}
+
+// Add adds the date of t, in the location of t.
+func (cal *Calendar) Add(t time.Time) {
+	y, m, d := t.Date()
+	cal.dates[date{y, m, d}] = struct{}{}
+}
+
+// AddRange adds the dates from the date of start through the date of end.
+func (cal *Calendar) AddRange(start, end time.Time) {
+	y, m, d := start.Date()
+	ey, em, ed := end.Date()
+	last := time.Date(ey, em, ed, 0, 0, 0, 0, time.UTC)
+	for t := time.Date(y, m, d, 0, 0, 0, 0, time.UTC); !t.After(last); t = t.AddDate(0, 0, 1) {
+		cal.Add(t)
+	}
+}
+
+// AddYearly adds the month and day of every year, e.g. December 25.
+func (cal *Calendar) AddYearly(month time.Month, day int) {
+	cal.yearly[date{0, month, day}] = struct{}{}
+}
+
+// Contains reports whether the date of t, in the location of t, is in the calendar.
+func (cal *Calendar) Contains(t time.Time) bool {
+	if cal == nil {
+		return false
+	}
+	y, m, d := t.Date()
+	_, ok := cal.dates[date{y, m, d}]
+	if !ok {
+		_, ok = cal.yearly[date{0, m, d}]
+	}
+	return ok
+}
+
+// Len returns the number of the dates, a yearly date counts once.
+func (cal *Calendar) Len() int {
+	if cal == nil {
+		return 0
+	}
+	return len(cal.dates) + len(cal.yearly)
+}
+
+func (cal *Calendar) String() string { return strconv.Itoa(cal.Len()) + " calendar dates" }
+
+// LoadCalendar reads the calendar file, see ParseCalendar.
+func LoadCalendar(path string) (*Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseCalendar(path, f)
+}
+
+// ParseCalendar parses a date per line, the path names the errors:
+//
+//	# comments and blank lines are ignored, the text after the date is a note
+//	2026-01-01 New Year's Day
+//	2026-10-01/2026-10-07 National Day, from and through
+//	12-25 Christmas, every year
+func ParseCalendar(path string, r io.Reader) (*Calendar, error) {
+	cal := NewCalendar()
+
+	var n int
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		n++
+		line := strs.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		field, _, _ := strings.Cut(strings.ReplaceAll(line, "\t", " "), " ")
+		from, to, ranged := strings.Cut(field, "/")
+		switch {
+		case ranged:
+			start, err := time.Parse(time.DateOnly, from)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+			end, err := time.Parse(time.DateOnly, to)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+			if end.Before(start) {
+				return nil, fmt.Errorf("%s:%d: range ends before it starts", path, n)
+			}
+			cal.AddRange(start, end)
+		case len(field) == len("01-02"):
+			t, err := time.Parse("01-02", field)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+			cal.AddYearly(t.Month(), t.Day())
+		default:
+			t, err := time.Parse(time.DateOnly, field)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+			cal.Add(t)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cal, nil
+}
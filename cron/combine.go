@@ -0,0 +1,108 @@
+package cron
+
+import (
+	"cmp"
+	"strings"
+	"time"
+)
+
+// Union returns a schedule activates at the times of any of the schedules.
+func Union(schedules ...Schedule) Schedule { return unionSchedule(schedules) }
+
+// Except returns a schedule activates at the times of s, but not on the dates of the calendar,
+// e.g. the public holidays. The dates are in the location of the times of s.
+func Except(s Schedule, calendar *Calendar) Schedule { return exceptSchedule{s, calendar} }
+
+// Between returns a schedule activates at the times of s from start, until before end. The
+// zero start or end is unbounded.
+func Between(s Schedule, start, end time.Time) Schedule { return betweenSchedule{s, start, end} }
+
+type unionSchedule []Schedule
+
+func (u unionSchedule) Next(t time.Time) (next time.Time) {
+	for _, s := range u {
+		if n := s.Next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return
+}
+
+func (u unionSchedule) String() string { return "any of " + describeAll(u) }
+
+type exceptSchedule struct {
+	s        Schedule
+	calendar *Calendar
+}
+
+func (e exceptSchedule) Next(t time.Time) time.Time {
+	horizon := t.AddDate(searchYears(e.s), 0, 0)
+	for next := e.s.Next(t); !next.IsZero() && next.Before(horizon); {
+		if !e.calendar.Contains(next) {
+			return next
+		}
+		// Skip the rest of the excluded day.
+		y, m, d := next.Date()
+		next = e.s.Next(time.Date(y, m, d+1, 0, 0, 0, 0, next.Location()).Add(-time.Nanosecond))
+	}
+	return time.Time{}
+}
+
+func (e exceptSchedule) String() string {
+	return DescribeSchedule(e.s) + " except " + e.calendar.String()
+}
+
+type betweenSchedule struct {
+	s          Schedule
+	start, end time.Time
+}
+
+func (b betweenSchedule) Next(t time.Time) time.Time {
+	if !b.start.IsZero() && t.Before(b.start) {
+		t = b.start.Add(-time.Nanosecond) // start itself may activate
+	}
+	next := b.s.Next(t)
+	if !b.end.IsZero() && !next.Before(b.end) {
+		return time.Time{}
+	}
+	return next
+}
+
+func (b betweenSchedule) String() string {
+	s := DescribeSchedule(b.s)
+	if !b.start.IsZero() {
+		s += " from " + b.start.Format(time.DateTime)
+	}
+	if !b.end.IsZero() {
+		s += " until " + b.end.Format(time.DateTime)
+	}
+	return s
+}
+
+// searchYears returns the years the schedule searches the next time within, see
+// SpecSchedule.SearchYears.
+func searchYears(s Schedule) int {
+	switch s := s.(type) {
+	case *SpecSchedule:
+		return cmp.Or(s.SearchYears, defaultSearchYears)
+	case unionSchedule:
+		var years int
+		for _, s := range s {
+			years = max(years, searchYears(s))
+		}
+		return cmp.Or(years, defaultSearchYears)
+	case exceptSchedule:
+		return searchYears(s.s)
+	case betweenSchedule:
+		return searchYears(s.s)
+	}
+	return defaultSearchYears
+}
+
+func describeAll(schedules []Schedule) string {
+	items := make([]string, len(schedules))
+	for i, s := range schedules {
+		items[i] = "(" + DescribeSchedule(s) + ")"
+	}
+	return strings.Join(items, ", ")
+}
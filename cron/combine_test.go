@@ -0,0 +1,50 @@
+package cron
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCombinators(t *testing.T) {
+	cal, err := ParseCalendar("holidays", strings.NewReader("# holidays\n2026-01-01 New Year's Day\n2026-01-05/2026-01-06\n01-08 yearly\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	weekdays, _ := ParseStandard("CRON_TZ=UTC 0 9 * * 1-5")
+	noon, _ := ParseStandard("CRON_TZ=UTC 0 12 * * *")
+	from := time.Date(2025, time.December, 31, 10, 0, 0, 0, time.UTC)
+
+	for _, c := range []struct {
+		name string
+		s    Schedule
+		want []string
+	}{
+		{"except", Except(weekdays, cal), []string{"2026-01-02 09:00", "2026-01-07 09:00", "2026-01-09 09:00"}},
+		{"union", Union(weekdays, noon), []string{"2025-12-31 12:00", "2026-01-01 09:00", "2026-01-01 12:00"}},
+		{"between", Between(noon, time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, time.March, 2, 12, 0, 0, 0, time.UTC)), []string{"2026-03-01 12:00"}},
+	} {
+		var got []string
+		for _, next := range NextN(c.s, from, 3) {
+			got = append(got, next.Format("2006-01-02 15:04"))
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	// The search of Except is limited by the search years of the schedule.
+	long := NewCalendar()
+	long.AddRange(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC))
+	for years, want := range map[int]string{1: "0001-01-01", 0: "2027-07-01"} {
+		s, _ := NewParser(Minute | Hour | Dom | Month | Dow).WithSearchYears(years).Parse("CRON_TZ=UTC 0 9 * * *")
+		if got := Except(s, long).Next(from).Format(time.DateOnly); got != want {
+			t.Errorf("except within %d years: got %s, want %s", years, got, want)
+		}
+	}
+
+	if _, err := ParseCalendar("bad", strings.NewReader("2026-13-01\n")); err == nil || !strings.HasPrefix(err.Error(), "bad:1: ") {
+		t.Errorf("bad date: got %v", err)
+	}
+}
@@ -1,7 +1,13 @@
 package cron
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"runtime/debug"
 	"slices"
 	"sync"
 	"sync/atomic"
@@ -10,50 +16,276 @@ import (
 	"github.com/cnk3x/gox/chans"
 )
 
+// Cron schedules the entries, it is safe to add, remove and inspect the entries from any
+// goroutine, while it is running or not.
 type Cron struct {
 	entries []*Entry
 	parser  Parser
 	local   *time.Location
+	clock   chans.Clock
 
-	addch    chan *Entry
-	closeAdd func()
+	searchYears int // applied to the parser in New, see WithSearchYears
 
-	delch    chan *Entry
-	closeDel func()
+	wake chan struct{} // wakes the run loop after the entries changed
 
 	stopch    chan struct{}
 	closeStop func()
 
+	ctx    context.Context // jobs context, canceled on stop
+	cancel context.CancelFunc
+
+	onSuccess []func(e Entry)
+	onError   []func(e Entry, err error)
+	onPanic   []func(e Entry, err *PanicError)
+
 	id      atomic.Uint64
 	running atomic.Bool
+	stopped bool                // set by Stop, no more jobs are started, guarded by mu
+	active  map[*Entry]struct{} // entries with running jobs, guarded by mu
+
+	locker  Locker        // grants the ticks among schedulers, see WithLocker
+	lockTTL time.Duration // lease of the locker
+
+	state string               // path of the state file, see WithState
+	prevs map[string]time.Time // last run times by entry name if state is set, guarded by mu
 
 	wg sync.WaitGroup
 	mu sync.Mutex
 }
 
-func New() *Cron {
+// Job is a scheduled function, ctx is canceled when the cron stops.
+type Job func(ctx context.Context) error
+
+// Func returns a Job runs fn.
+func Func(fn func()) Job { return func(context.Context) error { fn(); return nil } }
+
+// PanicError is the error of a job panicked.
+type PanicError struct {
+	Value any    // the value passed to panic
+	Stack []byte // the stack of the panicked goroutine
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack) }
+
+// Overlap is the policy of an entry when it is due while the previous run is still going.
+type Overlap int
+
+const (
+	OverlapAllow Overlap = iota // run concurrently
+	OverlapSkip                 // drop the tick
+	OverlapQueue                // run after the previous run finishes, pending ticks are coalesced into one run
+)
+
+func (o Overlap) String() string {
+	switch o {
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	default:
+		return "allow"
+	}
+}
+
+// Misfire is the policy of an entry when its run was missed, e.g. the host was asleep or
+// the process was down.
+type Misfire int
+
+const (
+	MisfireOnce Misfire = iota // run once for all the missed runs
+	MisfireAll                 // run every missed run, at most maxCatchUp runs
+	MisfireSkip                // skip the missed runs
+)
+
+func (m Misfire) String() string {
+	switch m {
+	case MisfireAll:
+		return "all"
+	case MisfireSkip:
+		return "skip"
+	default:
+		return "once"
+	}
+}
+
+const (
+	misfireGrace = time.Second // a run is missed if it is late more than this
+	stopGrace    = time.Second // the jobs are waited after their contexts are canceled on stop
+	maxCatchUp   = 1000        // limit of the runs by MisfireAll in one go
+)
+
+type Option func(*Cron)
+
+// WithClock sets the clock of the schedules and @once specs, defaults to chans.SystemClock.
+// The timeouts of the jobs use the real time.
+func WithClock(clock chans.Clock) Option { return func(c *Cron) { c.clock = clock } }
+
+// WithParser sets the parser of the specs, defaults to the optional seconds parser with
+// descriptors and years.
+func WithParser(p Parser) Option { return func(c *Cron) { c.parser = p } }
+
+// WithSearchYears sets the years the schedules search the next time within, see
+// SpecSchedule.SearchYears. It applies to the parser of WithParser too, in any order.
+func WithSearchYears(years int) Option { return func(c *Cron) { c.searchYears = years } }
+
+// WithLocker sets the locker consulted before each run, so a tick of an entry is run by only
+// one of the schedulers sharing the locker, e.g. replicas of a service. The entries are locked
+// by name, the lease lasts for ttl or the timeout of the entry, whichever is longer.
+func WithLocker(locker Locker, ttl time.Duration) Option {
+	return func(c *Cron) { c.locker, c.lockTTL = locker, ttl }
+}
+
+// WithState sets the state file keeps the last run time of the entries by name, so the runs
+// missed while the process was down are handled by the misfire policy at start.
+func WithState(path string) Option { return func(c *Cron) { c.state = path } }
+
+// EntryOption configures an entry on Add.
+type EntryOption func(*Entry)
+
+// WithOverlap sets the overlap policy of the entry, defaults to OverlapAllow.
+func WithOverlap(o Overlap) EntryOption { return func(e *Entry) { e.Overlap = o } }
+
+// WithMisfire sets the misfire policy of the entry, defaults to MisfireOnce.
+func WithMisfire(m Misfire) EntryOption { return func(e *Entry) { e.Misfire = m } }
+
+// WithTimeout sets the timeout of each run, the job context is canceled after it.
+func WithTimeout(d time.Duration) EntryOption { return func(e *Entry) { e.Timeout = d } }
+
+// OnSuccess adds a hook called after a job returned nil.
+func OnSuccess(fn func(e Entry)) Option {
+	return func(c *Cron) { c.onSuccess = append(c.onSuccess, fn) }
+}
+
+// OnError adds a hook called after a job returned an error.
+// Without hooks, the error is logged.
+func OnError(fn func(e Entry, err error)) Option {
+	return func(c *Cron) { c.onError = append(c.onError, fn) }
+}
+
+// OnPanic adds a hook called after a job panicked, the panic is recovered.
+// Without hooks, the panic is logged with the stack.
+func OnPanic(fn func(e Entry, err *PanicError)) Option {
+	return func(c *Cron) { c.onPanic = append(c.onPanic, fn) }
+}
+
+func New(options ...Option) *Cron {
 	c := &Cron{}
 	c.local = time.Local
 	c.parser = optionalParser
-	c.addch, c.closeAdd = chans.MakeChan[*Entry](5)
-	c.delch, c.closeDel = chans.MakeChan[*Entry](5)
+	c.clock = chans.SystemClock
+	c.wake = make(chan struct{}, 1)
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.active = map[*Entry]struct{}{}
 
 	c.stopch, c.closeStop = chans.StructChan()
+	for _, apply := range options {
+		apply(c)
+	}
+	c.parser = c.parser.WithClock(c.clock)
+	if c.searchYears > 0 {
+		c.parser = c.parser.WithSearchYears(c.searchYears)
+	}
+
+	c.prevs = map[string]time.Time{}
+	if c.state != "" {
+		prevs, err := loadState(c.state)
+		if err != nil {
+			slog.Warn("[cron] load state", "path", c.state, "err", err)
+		}
+		for name, prev := range prevs {
+			c.prevs[name] = prev
+		}
+	}
 	return c
 }
 
-func (c *Cron) Add(name, spec string, jobFn func()) (err error) {
-	shedule, e := c.parser.Parse(spec)
-	if err = e; err != nil {
+// Add adds a job, and returns the id of the entry.
+func (c *Cron) Add(name, spec string, job Job, options ...EntryOption) (id uint64, err error) {
+	schedule, err := c.parser.Parse(spec)
+	if err != nil {
 		return
 	}
+	return c.add(name, spec, schedule, job, options), nil
+}
 
-	entry := &Entry{ID: c.id.Add(1), Name: name, Cron: spec, Fn: jobFn, Schedule: shedule}
+// AddSchedule adds a job on the schedule, e.g. the combined ones, and returns the id of the
+// entry. The Cron of the entry is the description of the schedule.
+func (c *Cron) AddSchedule(name string, schedule Schedule, job Job, options ...EntryOption) uint64 {
+	return c.add(name, DescribeSchedule(schedule), schedule, job, options)
+}
 
+func (c *Cron) add(name, spec string, schedule Schedule, job Job, options []EntryOption) uint64 {
+	entry := &Entry{ID: c.id.Add(1), Name: name, Cron: spec, Job: job, Schedule: schedule, historySize: defaultHistory}
+	for _, apply := range options {
+		apply(entry)
+	}
+
+	c.mu.Lock()
+	entry.Prev = c.prevs[name]
 	if c.running.Load() {
-		c.addch <- entry
-	} else {
-		c.entries = append(c.entries, entry)
+		c.schedule(entry, c.now())
+	}
+	c.entries = append(c.entries, entry)
+	c.mu.Unlock()
+
+	c.notify()
+	slog.Info("[cron] job added", "name", entry.Name, "id", entry.ID, "next", entry.Next)
+	return entry.ID
+}
+
+// AddFunc adds a plain function as a job, see Add.
+func (c *Cron) AddFunc(name, spec string, fn func(), options ...EntryOption) (id uint64, err error) {
+	return c.Add(name, spec, Func(fn), options...)
+}
+
+// Remove removes the entry by id, it reports whether the entry was found.
+func (c *Cron) Remove(id uint64) bool {
+	return c.remove(func(e *Entry) bool { return e.ID == id }) > 0
+}
+
+// RemoveByName removes all entries with the name, it returns the number of removed entries.
+func (c *Cron) RemoveByName(name string) int {
+	return c.remove(func(e *Entry) bool { return e.Name == name })
+}
+
+func (c *Cron) remove(match func(e *Entry) bool) (n int) {
+	c.mu.Lock()
+	c.entries = slices.DeleteFunc(c.entries, func(e *Entry) bool {
+		if match(e) {
+			n++
+			slog.Info("[cron] job deled", "name", e.Name, "id", e.ID, "next", e.Next)
+			return true
+		}
+		return false
+	})
+	c.mu.Unlock()
+
+	if n > 0 {
+		c.notify()
+	}
+	return
+}
+
+// Entries returns a snapshot of the entries, ordered by the next run time.
+func (c *Cron) Entries() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]Entry, len(c.entries))
+	for i, e := range c.entries {
+		entries[i] = e.snapshot()
+	}
+	slices.SortStableFunc(entries, func(a, b Entry) int { return compareNext(&a, &b) })
+	return entries
+}
+
+// Entry returns a snapshot of the entry by id.
+func (c *Cron) Entry(id uint64) (entry Entry, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if i := slices.IndexFunc(c.entries, func(e *Entry) bool { return e.ID == id }); i >= 0 {
+		return c.entries[i].snapshot(), true
 	}
 	return
 }
@@ -65,68 +297,82 @@ func (c *Cron) Run() {
 }
 
 func (c *Cron) now() time.Time {
-	now := time.Now()
+	now := c.clock.Now()
 	if c.local != nil {
 		now = now.In(c.local)
 	}
 	return now
 }
 
+// notify wakes the run loop, it does not block.
+func (c *Cron) notify() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
 func (c *Cron) run() {
 	slog.Info("[cron] starting")
-	defer func() {
-		c.closeStop()
-		c.closeAdd()
-		slog.Info("[cron] stopped")
-	}()
+	defer slog.Info("[cron] stopped")
 
+	c.mu.Lock()
 	now := c.now()
 	for _, entry := range c.entries {
-		entry.Next = entry.Schedule.Next(now)
-		slog.Info("[cron] schedule", "name", entry.Name, "next", entry.Next)
+		c.schedule(entry, now)
 	}
+	c.mu.Unlock()
 
 	for {
 		// Determine the next entry to run.
-		slices.SortFunc(c.entries, func(a, b *Entry) int { return a.Next.Compare(b.Next) })
+		c.mu.Lock()
+		slices.SortFunc(c.entries, compareNext)
 
-		var timer *time.Timer
+		var timer chans.Timer
 		if len(c.entries) == 0 || c.entries[0].Next.IsZero() {
 			// If there are no entries yet, just sleep - it still handles new entries and stop requests.
-			timer = time.NewTimer(100000 * time.Hour)
+			timer = c.clock.NewTimer(100000 * time.Hour)
 		} else {
-			timer = time.NewTimer(c.entries[0].Next.Sub(now))
+			timer = c.clock.NewTimer(c.entries[0].Next.Sub(c.now()))
 		}
+		c.mu.Unlock()
 
 		select {
-		case now = <-timer.C:
+		case now = <-timer.C():
 			now = now.In(c.local)
 			slog.Info("[cron] wake", "now", now)
+
+			c.mu.Lock()
 			// Run every entry whose next time was less than now
 			for _, e := range c.entries {
 				if e.Next.After(now) || e.Next.IsZero() {
 					break
 				}
-				c.jonRun(e.Fn)
-				e.Prev = e.Next
-				e.Next = e.Schedule.Next(now)
+				c.fire(e, now)
+				if c.state != "" && !e.Prev.IsZero() {
+					c.prevs[e.Name] = e.Prev
+				}
+				slog.Info("[cron] job run", "name", e.Name, "id", e.ID, "next", e.Next)
+			}
+			prevs := maps.Clone(c.prevs)
+
+			c.entries = slices.DeleteFunc(c.entries, func(e *Entry) bool {
 				if e.Next.IsZero() {
 					slog.Info("[cron] job unsatisfiable, remove it", "name", e.Name, "id", e.ID)
-					c.delch <- e
+					return true
+				}
+				return false
+			})
+			c.mu.Unlock()
+
+			if c.state != "" {
+				if err := saveState(c.state, prevs); err != nil {
+					slog.Warn("[cron] save state", "path", c.state, "err", err)
 				}
-				slog.Info("[cron] job run", "name", e.Name, "id", e.ID, "next", e.Next)
 			}
-		case e := <-c.addch:
-			timer.Stop()
-			now = c.now()
-			e.Next = e.Schedule.Next(now)
-			c.entries = append(c.entries, e)
-			slog.Info("[cron] job added", "name", e.Name, "id", e.ID, "next", e.Next)
-		case e := <-c.delch:
+		case <-c.wake:
 			timer.Stop()
 			now = c.now()
-			c.entries = slices.DeleteFunc(c.entries, func(item *Entry) bool { return item.ID == e.ID })
-			slog.Info("[cron] job deled", "name", e.Name, "id", e.ID, "next", e.Next)
 		case <-c.stopch:
 			timer.Stop()
 			slog.Info("[cron] stop")
@@ -135,24 +381,224 @@ func (c *Cron) run() {
 	}
 }
 
-func (c *Cron) jonRun(j func()) {
+// schedule sets the first next time of the entry, c.mu must be held.
+// The run missed since Prev is due at once, unless the misfire policy is MisfireSkip.
+func (c *Cron) schedule(e *Entry, now time.Time) {
+	e.Next = e.Schedule.Next(now)
+	if !e.Prev.IsZero() && e.Misfire != MisfireSkip {
+		if missed := e.Schedule.Next(e.Prev); !missed.IsZero() && missed.Before(now) {
+			e.Next = missed
+		}
+	}
+	slog.Info("[cron] schedule", "name", e.Name, "next", e.Next, "prev", e.Prev)
+}
+
+// fire runs the due entry by the misfire policy, and moves it to the next time, c.mu must be held.
+func (c *Cron) fire(e *Entry, now time.Time) {
+	switch late := now.Sub(e.Next) > misfireGrace; {
+	case !late:
+		c.dispatch(e, e.Next)
+		e.Prev = e.Next
+	case e.Misfire == MisfireOnce:
+		c.dispatch(e, e.Next)
+		e.Prev = now // covers all the missed runs
+		slog.Warn("[cron] job misfired, run once", "name", e.Name, "id", e.ID, "from", e.Next)
+	case e.Misfire == MisfireAll:
+		n := 0
+		for t := e.Next; !t.IsZero() && !t.After(now) && n < maxCatchUp; t = e.Schedule.Next(t) {
+			c.dispatch(e, t)
+			e.Prev = t
+			n++
+		}
+		if n == maxCatchUp {
+			e.Prev = now // the rest are skipped
+		}
+		slog.Warn("[cron] job misfired, catch up", "name", e.Name, "id", e.ID, "from", e.Next, "runs", n)
+	default:
+		slog.Warn("[cron] job misfired, skip", "name", e.Name, "id", e.ID, "at", e.Next)
+	}
+	e.Next = e.Schedule.Next(now)
+}
+
+// dispatch runs the tick of the due entry by the overlap policy, c.mu must be held.
+func (c *Cron) dispatch(e *Entry, tick time.Time) {
+	switch {
+	case c.stopped: // no more runs after Stop
+	case e.Running == 0 || e.Overlap == OverlapAllow:
+		c.jobRun(e, tick)
+	case e.Overlap == OverlapQueue && e.queued.IsZero():
+		e.queued = tick
+	default:
+		e.Skipped++
+		e.record(Execution{Tick: tick, Skipped: true})
+		slog.Warn("[cron] job still running, tick skipped", "name", e.Name, "id", e.ID, "overlap", e.Overlap, "skipped", e.Skipped)
+	}
+}
+
+// jobRun runs the entry in a new goroutine, and the queued run after it, c.mu must be held.
+func (c *Cron) jobRun(e *Entry, tick time.Time) {
+	e.Running++
+	c.active[e] = struct{}{}
+	snapshot := e.snapshot()
+
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
-		func() {
-			defer recover()
-			j()
-		}()
+
+		for {
+			start := c.now()
+			ran, err := c.execute(snapshot, tick)
+
+			c.mu.Lock()
+			if ran {
+				e.record(Execution{Tick: tick, Start: start, End: c.now(), Err: err})
+			} else {
+				e.record(Execution{Tick: tick, Skipped: true})
+			}
+			done := e.snapshot()
+			next := !e.queued.IsZero() && !c.stopped && slices.Contains(c.entries, e)
+			if next {
+				tick, e.queued = e.queued, time.Time{}
+				snapshot = done
+			} else {
+				e.queued = time.Time{}
+				if e.Running--; e.Running == 0 {
+					delete(c.active, e)
+				}
+			}
+			c.mu.Unlock()
+
+			if ran {
+				c.report(done, err)
+			}
+			if !next {
+				return
+			}
+		}
+	}()
+}
+
+// execute calls the job of the tick, under a lease of the locker if set. It reports false
+// if the tick is claimed by another scheduler.
+func (c *Cron) execute(e Entry, tick time.Time) (ran bool, err error) {
+	if c.locker == nil {
+		return true, c.call(c.ctx, e)
+	}
+
+	token, ok, err := c.locker.Lock(c.ctx, e.Name, tick, max(c.lockTTL, e.Timeout))
+	if err != nil {
+		return true, fmt.Errorf("lock: %w", err)
+	}
+	if !ok {
+		slog.Info("[cron] tick claimed by another", "name", e.Name, "id", e.ID, "tick", tick)
+		return false, nil
+	}
+
+	defer func() {
+		if err := c.locker.Unlock(context.WithoutCancel(c.ctx), e.Name, token); err != nil {
+			slog.Warn("[cron] unlock", "name", e.Name, "id", e.ID, "token", token, "err", err)
+		}
+	}()
+	return true, c.call(context.WithValue(c.ctx, tokenKey{}, token), e)
+}
+
+// call calls the job, a panic is recovered as a *PanicError.
+func (c *Cron) call(ctx context.Context, e Entry) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
 	}()
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+	return e.Job(ctx)
+}
+
+// report calls the hooks by the result of the job.
+func (c *Cron) report(e Entry, err error) {
+	var pe *PanicError
+	switch {
+	case err == nil:
+		for _, fn := range c.onSuccess {
+			fn(e)
+		}
+	case errors.As(err, &pe):
+		for _, fn := range c.onPanic {
+			fn(e, pe)
+		}
+		if len(c.onPanic) == 0 {
+			slog.Error("[cron] job panic", "name", e.Name, "id", e.ID, "panic", pe.Value, "stack", string(pe.Stack))
+		}
+	default:
+		for _, fn := range c.onError {
+			fn(e, err)
+		}
+		if len(c.onError) == 0 {
+			slog.Warn("[cron] job failed", "name", e.Name, "id", e.ID, "err", err)
+		}
+	}
 }
 
-func (c *Cron) Stop() { c.closeStop() }
+// Stop stops scheduling, and waits for the running jobs until ctx is done, then the job
+// contexts are canceled and the jobs are waited for stopGrace more. It returns the entries
+// of the jobs still running, which did not finish in time.
+func (c *Cron) Stop(ctx context.Context) (unfinished []Entry) {
+	c.mu.Lock()
+	c.stopped = true
+	c.mu.Unlock()
+	c.closeStop()
+	defer c.cancel()
+
+	done, closeDone := chans.StructChan()
+	go func() { c.wg.Wait(); closeDone() }()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	c.cancel()
+	select {
+	case <-done:
+		return
+	case <-time.After(stopGrace):
+	}
+
+	c.mu.Lock()
+	for e := range c.active {
+		unfinished = append(unfinished, e.snapshot())
+	}
+	c.mu.Unlock()
+
+	slices.SortFunc(unfinished, func(a, b Entry) int { return cmp.Compare(a.ID, b.ID) })
+	for _, e := range unfinished {
+		slog.Warn("[cron] job unfinished on stop", "name", e.Name, "id", e.ID, "running", e.Running)
+	}
+	return
+}
+
+// compareNext orders entries by the next time, the zero time (unscheduled) at the end.
+func compareNext(a, b *Entry) int {
+	switch {
+	case a.Next.IsZero() && b.Next.IsZero():
+		return 0
+	case a.Next.IsZero():
+		return 1
+	case b.Next.IsZero():
+		return -1
+	}
+	return a.Next.Compare(b.Next)
+}
 
 type Entry struct {
 	Name string
 	ID   uint64
 	Cron string
-	Fn   func()
+	Job  Job
 
 	// Schedule on which this job should be run.
 	Schedule Schedule
@@ -163,4 +609,19 @@ type Entry struct {
 
 	// Prev is the last time this job was run, or the zero time if never.
 	Prev time.Time
+
+	Overlap Overlap       // policy when the entry is due while it is running
+	Misfire Misfire       // policy when a run of the entry was missed
+	Timeout time.Duration // timeout of each run, 0 for no timeout
+	Running int           // number of running jobs
+	Skipped uint64        // number of ticks skipped by the overlap policy
+
+	// History of the recent executions, oldest first, set in the snapshots only.
+	History []Execution
+	Stats   Stats
+
+	queued      time.Time   // the tick queued by OverlapQueue, zero if none
+	history     []Execution // ring of the recent executions, see record
+	head        int         // the oldest of the full ring
+	historySize int
 }
@@ -0,0 +1,385 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cnk3x/gox/chans"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.Local)
+	clock := chans.NewFakeClock(start)
+
+	// An overdue timer delivers the current time, as time.Timer.
+	if at := <-clock.NewTimer(-time.Minute).C(); !at.Equal(start) {
+		t.Errorf("overdue timer: got %s, want %s", at, start)
+	}
+
+	c := New(WithClock(clock))
+
+	runs := make(chan string, 10)
+	if _, err := c.AddFunc("every", "0 */5 * * * *", func() { runs <- "every" }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddFunc("once", "@once 7m", func() { runs <- "once" }); err != nil {
+		t.Fatal(err)
+	}
+
+	go c.Run()
+	defer c.Stop(context.Background())
+
+	for _, step := range []struct {
+		at   time.Duration
+		want string
+	}{{5 * time.Minute, "every"}, {7 * time.Minute, "once"}, {10 * time.Minute, "every"}} {
+		clock.BlockUntil(1)
+		clock.Set(start.Add(step.at))
+		select {
+		case got := <-runs:
+			if got != step.want {
+				t.Errorf("%s: run %s, want %s", step.at, got, step.want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: no run, want %s", step.at, step.want)
+		}
+	}
+
+	select {
+	case got := <-runs:
+		t.Errorf("unexpected run %s", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLocker(t *testing.T) {
+	for name, locker := range map[string]Locker{"memory": NewMemoryLocker(), "file": NewFileLocker(t.TempDir())} {
+		t.Run(name, func(t *testing.T) {
+			start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.Local)
+			clock := chans.NewFakeClock(start)
+
+			runs := make(chan uint64, 10)
+			for range 2 {
+				c := New(WithClock(clock), WithLocker(locker, time.Minute))
+				_, err := c.Add("job", "0 * * * * *", func(ctx context.Context) error {
+					token, _ := LockToken(ctx)
+					runs <- token
+					return nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				go c.Run()
+				defer c.Stop(context.Background())
+			}
+
+			for i := range 3 {
+				clock.BlockUntil(2)
+				clock.Set(start.Add(time.Duration(i+1) * time.Minute))
+				select {
+				case token := <-runs:
+					if token != uint64(i+1) {
+						t.Errorf("tick %d: token %d", i+1, token)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("tick %d: no run", i+1)
+				}
+			}
+
+			select {
+			case token := <-runs:
+				t.Errorf("tick run twice, token %d", token)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.Local)
+	clock := chans.NewFakeClock(start)
+
+	done := make(chan Entry, 10)
+	c := New(WithClock(clock), OnSuccess(func(e Entry) { done <- e }), OnError(func(e Entry, err error) { done <- e }))
+
+	var n int
+	id, err := c.Add("job", "0 * * * * *", func(context.Context) error {
+		if n++; n%2 == 0 {
+			return errors.New("even")
+		}
+		clock.Advance(time.Second) // fires no timer, the next is a minute away
+		return nil
+	}, WithHistory(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go c.Run()
+	defer c.Stop(context.Background())
+
+	for i := range 3 {
+		clock.BlockUntil(1)
+		clock.Set(start.Add(time.Duration(i+1) * time.Minute))
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("run %d: timeout", i+1)
+		}
+	}
+
+	e, _ := c.Entry(id)
+	if s := e.Stats; s.Runs != 3 || s.Failures != 1 || s.Consecutive != 0 || s.Max != time.Second || s.Avg() != 2*time.Second/3 {
+		t.Errorf("stats %+v", s)
+	}
+	if len(e.History) != 2 || e.History[0].Err == nil || e.History[1].Err != nil || !e.History[1].Tick.Equal(start.Add(3*time.Minute)) {
+		t.Errorf("history %+v", e.History)
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	for _, c := range []struct {
+		overlap Overlap
+		runs    uint64
+		skipped uint64
+	}{
+		{OverlapSkip, 2, 2},  // the ticks 2 and 3 are dropped
+		{OverlapQueue, 3, 1}, // the tick 2 is queued, and the tick 3 coalesced into it
+	} {
+		t.Run(c.overlap.String(), func(t *testing.T) {
+			start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.Local)
+			clock := chans.NewFakeClock(start)
+			done := make(chan struct{}, 10)
+			cr := New(WithClock(clock), OnSuccess(func(Entry) { done <- struct{}{} }))
+
+			started, release := make(chan struct{}, 10), make(chan struct{})
+			id, err := cr.Add("job", "0 * * * * *", func(context.Context) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			}, WithOverlap(c.overlap))
+			if err != nil {
+				t.Fatal(err)
+			}
+			go cr.Run()
+			defer cr.Stop(context.Background())
+
+			recv := func(ch <-chan struct{}) {
+				t.Helper()
+				select {
+				case <-ch:
+				case <-time.After(time.Second):
+					t.Fatal("timeout")
+				}
+			}
+
+			for i := range 3 {
+				clock.BlockUntil(1)
+				clock.Set(start.Add(time.Duration(i+1) * time.Minute))
+				if i == 0 {
+					recv(started)
+				}
+			}
+			clock.BlockUntil(1) // the ticks are dispatched
+			close(release)
+			recv(done)
+			if c.overlap == OverlapQueue {
+				recv(started)
+				recv(done)
+			}
+			clock.Set(start.Add(4 * time.Minute))
+			recv(started)
+			recv(done)
+
+			e, _ := cr.Entry(id)
+			if e.Stats.Runs != c.runs || e.Skipped != c.skipped {
+				t.Errorf("runs %d, skipped %d, want %d, %d", e.Stats.Runs, e.Skipped, c.runs, c.skipped)
+			}
+		})
+	}
+}
+
+func TestTimeoutStop(t *testing.T) {
+	start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.Local)
+	clock := chans.NewFakeClock(start)
+	errs := make(chan error, 10)
+	c := New(WithClock(clock), OnError(func(e Entry, err error) { errs <- err }))
+
+	// The timeout uses the real time.
+	if _, err := c.Add("timeout", "0 * * * * *", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(10*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	stuck, canceled := make(chan error, 1), make(chan struct{})
+	if _, err := c.Add("stuck", "30 * * * * *", func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release
+		stuck <- ctx.Err()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Add("canceled", "30 * * * * *", func(ctx context.Context) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	go c.Run()
+	clock.BlockUntil(1)
+	clock.Set(start.Add(time.Minute))
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("timeout: got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout: no error")
+	}
+
+	clock.BlockUntil(1)
+	clock.Set(start.Add(time.Minute + 30*time.Second))
+	<-started
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	unfinished := c.Stop(ctx)
+	if len(unfinished) != 1 || unfinished[0].Name != "stuck" || unfinished[0].Running != 1 {
+		t.Errorf("unfinished %+v", unfinished)
+	}
+
+	// The job honoring its context finished within the grace, before Stop returned.
+	select {
+	case <-canceled:
+	default:
+		t.Error("canceled: not finished on stop")
+	}
+
+	// The context of the stuck job was canceled too.
+	close(release)
+	if err := <-stuck; !errors.Is(err, context.Canceled) {
+		t.Errorf("stuck: ctx %v", err)
+	}
+}
+
+func TestMisfire(t *testing.T) {
+	start := time.Date(2026, time.January, 1, 0, 30, 0, 0, time.Local)
+	hour := func(h, m int) time.Time { return time.Date(2026, time.January, 1, h, m, 0, 0, time.Local) }
+
+	// The state file keeps the run at 01:00.
+	state := filepath.Join(t.TempDir(), "state.json")
+	clock := chans.NewFakeClock(start)
+	c := New(WithClock(clock), WithState(state))
+	c.AddFunc("job", "0 0 * * * *", func() {})
+	go c.Run()
+	clock.BlockUntil(1)
+	clock.Set(hour(1, 0))
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if prevs, _ := loadState(state); prevs["job"].Equal(hour(1, 0)) {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("state %v", prevs)
+		}
+	}
+	c.Stop(context.Background())
+	data, _ := os.ReadFile(state)
+
+	// Restarted at 04:30, the runs at 02:00, 03:00 and 04:00 are missed.
+	for _, m := range []struct {
+		misfire Misfire
+		runs    int
+		prev    time.Time
+	}{
+		{MisfireOnce, 1, hour(4, 30)},
+		{MisfireAll, 3, hour(4, 0)},
+		{MisfireSkip, 0, hour(1, 0)},
+	} {
+		t.Run(m.misfire.String(), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.json")
+			os.WriteFile(path, data, 0o666)
+
+			clock := chans.NewFakeClock(hour(4, 30))
+			runs := make(chan struct{}, 10)
+			c := New(WithClock(clock), WithState(path))
+			id, _ := c.AddFunc("job", "0 0 * * * *", func() { runs <- struct{}{} }, WithMisfire(m.misfire))
+			go c.Run()
+			defer c.Stop(context.Background())
+
+			for i := range m.runs {
+				select {
+				case <-runs:
+				case <-time.After(time.Second):
+					t.Fatalf("run %d: timeout", i+1)
+				}
+			}
+			select {
+			case <-runs:
+				t.Error("unexpected run")
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			if e, _ := c.Entry(id); !e.Prev.Equal(m.prev) || !e.Next.Equal(hour(5, 0)) {
+				t.Errorf("prev %s, next %s", e.Prev, e.Next)
+			}
+		})
+	}
+}
+
+func TestReAdd(t *testing.T) {
+	start := time.Date(2026, time.January, 1, 0, 30, 0, 0, time.Local)
+	clock := chans.NewFakeClock(start)
+	runs := make(chan struct{}, 10)
+	c := New(WithClock(clock))
+	go c.Run()
+	defer c.Stop(context.Background())
+
+	c.AddFunc("job", "0 0 * * * *", func() { runs <- struct{}{} })
+	clock.BlockUntil(1)
+	clock.Set(start.Add(30 * time.Minute))
+	<-runs
+
+	// Without a state file, the entry added again under the name has no missed runs.
+	c.RemoveByName("job")
+	clock.Set(start.Add(3 * time.Hour))
+	id, _ := c.AddFunc("job", "0 0 * * * *", func() { runs <- struct{}{} })
+	select {
+	case <-runs:
+		t.Error("run of the removed entry")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if e, _ := c.Entry(id); !e.Prev.IsZero() {
+		t.Errorf("prev %s", e.Prev)
+	}
+}
+
+func TestSearchYears(t *testing.T) {
+	// The search years apply to the parser, whatever the order of the options.
+	parser := NewParser(Second | Minute | Hour | Dom | Month | Dow)
+	for _, options := range [][]Option{
+		{WithSearchYears(1)},
+		{WithSearchYears(1), WithParser(parser)},
+		{WithParser(parser), WithSearchYears(1)},
+	} {
+		c := New(options...)
+		id, err := c.AddFunc("job", "0 0 0 29 2 *", func() {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, _ := c.Entry(id)
+		from := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.Local)
+		if next := e.Schedule.Next(from); !next.IsZero() {
+			t.Errorf("next February 29 within a year: got %s", next)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package cron
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cnk3x/gox/strs"
+)
+
+// Crontab is a crontab file, see LoadCrontab.
+type Crontab struct {
+	Path  string
+	Lines []CrontabLine
+}
+
+// CrontabLine is a job line of a crontab.
+type CrontabLine struct {
+	Line    int      // line number, from 1
+	Spec    string   // cron spec, with the CRON_TZ in effect
+	Command string   // the rest of the line
+	Env     []string // the assignments in effect, e.g. "PATH=/usr/bin:/bin", "SHELL=/bin/bash"
+}
+
+// CommandRunner runs a command of a crontab line, env are the assignments of the crontab in
+// effect, e.g. cmdx.CrontabRunner.
+type CommandRunner func(ctx context.Context, command string, env []string) error
+
+// LoadCrontab reads the crontab file, see ParseCrontab.
+func LoadCrontab(path string) (*Crontab, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseCrontab(path, f)
+}
+
+var (
+	crontabEnv = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+	dowPart    = regexp.MustCompile(`^(?i)([0-9*?]*|sun|mon|tue|wed|thu|fri|sat)l?$`)
+)
+
+// ParseCrontab parses the classic crontab syntax, the path names the errors and the jobs:
+//
+//	# comments and blank lines are ignored
+//	SHELL=/bin/bash
+//	CRON_TZ=Asia/Shanghai
+//	*/5 * * * * command
+//	0 */5 * * * * command
+//	@daily command
+//	@every 1h command
+//
+// Assignments apply to the following lines, the quotes around the values are removed, and
+// CRON_TZ sets the time zone of the following specs. A line has 5 fields (minute to day of
+// week), or 6 fields with the seconds first if the sixth field looks like a day of week.
+// The day of week 7 is Sunday, as 0. The @reboot lines are skipped with a warning, and the %
+// of the commands is not special.
+func ParseCrontab(path string, r io.Reader) (tab *Crontab, err error) {
+	tab = &Crontab{Path: path}
+
+	var (
+		env []string
+		tz  string
+		n   int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		n++
+		line := strs.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if m := crontabEnv.FindStringSubmatch(line); m != nil {
+			name, value := m[1], unquote(m[2])
+			if name == "CRON_TZ" {
+				if _, err = time.LoadLocation(value); err != nil {
+					return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+				}
+				tz = value
+				continue
+			}
+			// Copy on write, the lines above keep their env.
+			env = slices.Clone(env)
+			if i := slices.IndexFunc(env, func(kv string) bool { return strs.HasPrefix(kv, name+"=") }); i >= 0 {
+				env[i] = name + "=" + value
+			} else {
+				env = append(env, name+"="+value)
+			}
+			continue
+		}
+
+		fields, command := cutFields(line, specFields(line))
+		if command == "" {
+			return nil, fmt.Errorf("%s:%d: missing command", path, n)
+		}
+		if strings.EqualFold(fields[0], "@reboot") {
+			slog.Warn("[cron] crontab @reboot is not supported, skipped", "path", path, "line", n)
+			continue
+		}
+		if len(fields) >= 5 {
+			fields[len(fields)-1] = sunday7(fields[len(fields)-1])
+		}
+
+		spec := strings.Join(fields, " ")
+		if tz != "" {
+			spec = "CRON_TZ=" + tz + " " + spec
+		}
+		if _, err = optionalParser.Parse(spec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+
+		tab.Lines = append(tab.Lines, CrontabLine{Line: n, Spec: spec, Command: command, Env: env})
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Add adds the lines to c as jobs named "path:line", the commands are run by run.
+func (tab *Crontab) Add(c *Cron, run CommandRunner, options ...EntryOption) error {
+	for _, l := range tab.Lines {
+		job := func(ctx context.Context) error { return run(ctx, l.Command, l.Env) }
+		if _, err := c.Add(tab.Path+":"+strconv.Itoa(l.Line), l.Spec, job, options...); err != nil {
+			return fmt.Errorf("%s:%d: %w", tab.Path, l.Line, err)
+		}
+	}
+	return nil
+}
+
+// specFields returns the number of the spec fields of the line.
+func specFields(line string) int {
+	fields := strs.Fields(line)
+	switch {
+	case fields[0] == "@every" || fields[0] == "@once":
+		return 2
+	case strs.HasPrefix(fields[0], "@"):
+		return 1
+	case len(fields) > 6 && isDowField(fields[5]):
+		return 6
+	}
+	return 5
+}
+
+// isDowField reports whether s looks like a day of week field, rather than a command.
+func isDowField(s string) bool {
+	for _, part := range strs.FieldsFunc(s, func(r rune) bool { return strings.ContainsRune(",-/#", r) }) {
+		if !dowPart.MatchString(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// sunday7 rewrites the day of week 7 as 0, e.g. "5-7" as "5-6,0", "7#2" as "0#2".
+func sunday7(field string) string {
+	parts := strings.Split(field, ",")
+	for i, part := range parts {
+		r, step, stepped := strings.Cut(part, "/")
+		from, to, ranged := strings.Cut(r, "-")
+		switch {
+		case !ranged && strs.HasPrefix(r, "7"):
+			parts[i] = "0" + part[1:]
+		case ranged && to == "7" && from == "7":
+			parts[i] = "0"
+		case ranged && to == "7":
+			parts[i] = from + "-6"
+			sunday := true
+			if stepped {
+				// 7 is in the range if the step from the start reaches it.
+				parts[i] += "/" + step
+				k, _ := strconv.Atoi(step)
+				a, err := strconv.Atoi(from)
+				if v, ok := dow.names[strs.Lower(from)]; ok {
+					a, err = int(v), nil
+				}
+				sunday = err == nil && k > 0 && (7-a)%k == 0
+			}
+			if sunday {
+				parts[i] += ",0"
+			}
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// cutFields returns the first n fields of the line, and the rest of it.
+func cutFields(line string, n int) (fields []string, rest string) {
+	rest = line
+	for range n {
+		rest = strings.TrimLeft(rest, " \t")
+		i := strings.IndexAny(rest, " \t")
+		if i < 0 {
+			return append(fields, rest), ""
+		}
+		fields, rest = append(fields, rest[:i]), rest[i:]
+	}
+	return fields, strs.TrimSpace(rest)
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package cron
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseCrontab(t *testing.T) {
+	tab, err := ParseCrontab("crontab", strings.NewReader(`# comment
+SHELL=/bin/bash
+PATH = "/usr/bin:/bin"
+*/5 * * * * echo five fields
+0 */5 * * * * echo six fields
+0 9 * * 1-5 echo 1-5 is a command
+CRON_TZ=Asia/Shanghai
+SHELL='/bin/sh'
+0 0 * * 7 sunday
+0 0 * * 5-7 weekend
+@reboot skipped
+@every 1h hourly
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bash, sh := []string{"SHELL=/bin/bash", "PATH=/usr/bin:/bin"}, []string{"SHELL=/bin/sh", "PATH=/usr/bin:/bin"}
+	want := []CrontabLine{
+		{4, "*/5 * * * *", "echo five fields", bash},
+		{5, "0 */5 * * * *", "echo six fields", bash},
+		{6, "0 9 * * 1-5", "echo 1-5 is a command", bash},
+		{9, "CRON_TZ=Asia/Shanghai 0 0 * * 0", "sunday", sh},
+		{10, "CRON_TZ=Asia/Shanghai 0 0 * * 5-6,0", "weekend", sh},
+		{12, "CRON_TZ=Asia/Shanghai @every 1h", "hourly", sh},
+	}
+	if len(tab.Lines) != len(want) {
+		t.Fatalf("lines %+v", tab.Lines)
+	}
+	for i, l := range tab.Lines {
+		if w := want[i]; l.Line != w.Line || l.Spec != w.Spec || l.Command != w.Command || !slices.Equal(l.Env, w.Env) {
+			t.Errorf("line %+v, want %+v", l, w)
+		}
+	}
+
+	for _, c := range []struct{ field, want string }{
+		{"7", "0"}, {"7L", "0L"}, {"7#2", "0#2"}, {"1-7", "1-6,0"}, {"7-7", "0"},
+		{"1-7/2", "1-6/2,0"}, {"2-7/2", "2-6/2"}, {"fri-7", "fri-6,0"}, {"*/2", "*/2"}, {"MON,7", "MON,0"},
+	} {
+		if got := sunday7(c.field); got != c.want {
+			t.Errorf("sunday7(%q) = %q, want %q", c.field, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"* * * * *\n", "CRON_TZ=Nowhere/City\n", "1 2 3\n"} {
+		if _, err := ParseCrontab("bad", strings.NewReader("# bad\n"+bad)); err == nil || !strings.HasPrefix(err.Error(), "bad:2: ") {
+			t.Errorf("%q: got %v", bad, err)
+		}
+	}
+}
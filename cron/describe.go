@@ -0,0 +1,386 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale renders the description of a schedule, see Describe.
+type Locale interface {
+	// Every describes a constant delay schedule, e.g. "@every 1h".
+	Every(d time.Duration) string
+	// Once describes a one-time schedule, e.g. "@once 10m".
+	Once(t time.Time) string
+	// Describe describes the fields of a spec schedule.
+	Describe(f Fields) string
+}
+
+// Fields are the analyzed fields of a SpecSchedule, for a Locale to describe.
+type Fields struct {
+	Second, Minute, Hour, Dom, Month, Dow, Year Values
+
+	LastDom        bool     // L
+	LastWeekday    bool     // LW
+	NearestWeekday []int    // nW, the days
+	LastDow        []int    // nL, the weekdays
+	NthDow         [][2]int // n#k, the weekday and k
+
+	// Location of the schedule, nil for the local time.
+	Location *time.Location
+}
+
+// DomRestricted reports whether the day of month is restricted.
+func (f Fields) DomRestricted() bool {
+	return !f.Dom.Any || f.LastDom || f.LastWeekday || len(f.NearestWeekday) > 0
+}
+
+// DowRestricted reports whether the day of week is restricted.
+func (f Fields) DowRestricted() bool {
+	return !f.Dow.Any || len(f.LastDow) > 0 || len(f.NthDow) > 0
+}
+
+// Values are the values of a field.
+type Values struct {
+	Any   bool   // every value, * or ?
+	Step  int    // every Step values from From to To, 0 if not stepped
+	From  int    // first value of the step
+	To    int    // last value of the step
+	Whole bool   // the step starts at the minimum and covers the whole range, e.g. */15
+	Spans []Span // the values and ranges, set if not Any, also for the stepped values
+}
+
+// Span is a value if From equals To, or a range of values.
+type Span struct{ From, To int }
+
+// Single returns the value if there is only one.
+func (v Values) Single() (int, bool) {
+	if len(v.Spans) == 1 && v.Spans[0].From == v.Spans[0].To {
+		return v.Spans[0].From, true
+	}
+	return 0, false
+}
+
+// Describe returns a human-readable description of the spec, in English by default.
+func Describe(spec string, locale ...Locale) (string, error) {
+	schedule, err := optionalParser.Parse(spec)
+	if err != nil {
+		return "", err
+	}
+	return DescribeSchedule(schedule, locale...), nil
+}
+
+// DescribeSchedule returns a human-readable description of the schedule, in English by default.
+func DescribeSchedule(schedule Schedule, locale ...Locale) string {
+	l := English
+	if len(locale) > 0 && locale[0] != nil {
+		l = locale[0]
+	}
+
+	switch s := schedule.(type) {
+	case ConstantDelaySchedule:
+		return l.Every(s.Delay)
+	case OnceSchedule:
+		return l.Once(s.Time)
+	case *SpecSchedule:
+		return l.Describe(describeFields(s))
+	}
+	return fmt.Sprint(schedule)
+}
+
+func describeFields(s *SpecSchedule) (f Fields) {
+	f.Second = describeBits(s.Second, seconds)
+	f.Minute = describeBits(s.Minute, minutes)
+	f.Hour = describeBits(s.Hour, hours)
+	f.Dom = describeBits(s.Dom, dom)
+	f.Month = describeBits(s.Month, months)
+	f.Dow = describeBits(s.Dow, dow)
+	f.Year = Values{Any: true}
+	if s.Years != nil {
+		f.Year = describeValues(s.Years, yearBounds)
+	}
+
+	// The star of one day field leaves the other one restrict the days, while the parsed
+	// Quartz rules leave their fields empty.
+	if s.Dom == 0 {
+		f.Dom = Values{Any: true}
+	}
+	if s.Dow == 0 {
+		f.Dow = Values{Any: true}
+	}
+
+	f.LastDom, f.LastWeekday = s.LastDom, s.LastWeekday
+	for day := dom.min; day <= dom.max; day++ {
+		if 1<<day&s.NearestWeekday > 0 {
+			f.NearestWeekday = append(f.NearestWeekday, int(day))
+		}
+	}
+	for wd := dow.min; wd <= dow.max; wd++ {
+		if 1<<wd&s.LastDow > 0 {
+			f.LastDow = append(f.LastDow, int(wd))
+		}
+		for k := 1; k <= 5; k++ {
+			if 1<<k&s.NthDow[wd] > 0 {
+				f.NthDow = append(f.NthDow, [2]int{int(wd), k})
+			}
+		}
+	}
+
+	if s.Location != time.Local {
+		f.Location = s.Location
+	}
+	return
+}
+
+func describeBits(bits uint64, r bounds) Values {
+	if bits&starBit > 0 {
+		return Values{Any: true}
+	}
+	var values []int
+	for i := r.min; i <= r.max; i++ {
+		if 1<<i&bits > 0 {
+			values = append(values, int(i))
+		}
+	}
+	return describeValues(values, r)
+}
+
+// describeValues describes the sorted values within the bounds.
+func describeValues(values []int, r bounds) (v Values) {
+	if len(values) == int(r.max-r.min+1) {
+		return Values{Any: true}
+	}
+
+	for _, n := range values {
+		if last := len(v.Spans) - 1; last >= 0 && v.Spans[last].To == n-1 {
+			v.Spans[last].To = n
+		} else {
+			v.Spans = append(v.Spans, Span{n, n})
+		}
+	}
+
+	if len(values) >= 3 {
+		step := values[1] - values[0]
+		for i := 2; i < len(values) && step > 1; i++ {
+			if values[i]-values[i-1] != step {
+				step = 0
+			}
+		}
+		if step > 1 {
+			v.Step, v.From, v.To = step, values[0], values[len(values)-1]
+			v.Whole = v.From == int(r.min) && v.To+step > int(r.max)
+		}
+	}
+	return
+}
+
+// English describes the schedules in English.
+var English Locale = english{}
+
+type english struct{}
+
+var enOrdinals = []string{"", "first", "second", "third", "fourth", "fifth"}
+
+func (english) Every(d time.Duration) string { return "every " + shortDuration(d) }
+
+func (english) Once(t time.Time) string { return "once at " + t.Format(time.DateTime) }
+
+func (l english) Describe(f Fields) string {
+	var b strings.Builder
+	b.WriteString(l.time(f))
+	for _, part := range []string{l.days(f), l.months(f.Month), l.years(f.Year)} {
+		if part != "" {
+			b.WriteString(", " + part)
+		}
+	}
+	if f.Location != nil {
+		b.WriteString(" (" + f.Location.String() + ")")
+	}
+	return b.String()
+}
+
+func (l english) time(f Fields) string {
+	h, hok := f.Hour.Single()
+	m, mok := f.Minute.Single()
+	s, sok := f.Second.Single()
+	if hok && mok && sok {
+		return "at " + clock(h, m, s)
+	}
+
+	// A few times of the day, e.g. "at 09:30 and 18:30".
+	if mok && sok && !f.Hour.Any && f.Hour.Step == 0 && len(f.Hour.Spans) <= 4 && singles(f.Hour.Spans) {
+		times := make([]string, len(f.Hour.Spans))
+		for i, span := range f.Hour.Spans {
+			times[i] = clock(span.From, m, s)
+		}
+		return "at " + enJoin(times)
+	}
+
+	var parts []string
+	hourly := mok && sok && m == 0 && s == 0 // on the hour, e.g. "every 2 hours"
+	switch {
+	case sok && s == 0:
+	case f.Second.Any:
+		parts = append(parts, "every second")
+	default:
+		parts = append(parts, l.clause(f.Second, "second", strconv.Itoa))
+	}
+
+	switch {
+	case hourly:
+	case f.Minute.Any && len(parts) == 0:
+		parts = append(parts, "every minute")
+	case f.Minute.Any && !f.Second.Any:
+		parts = append(parts, "of every minute")
+	case f.Minute.Any:
+	default:
+		parts = append(parts, l.clause(f.Minute, "minute", strconv.Itoa))
+	}
+
+	switch {
+	case hourly && f.Hour.Any:
+		parts = append(parts, "every hour")
+	case f.Hour.Any && !f.Minute.Any && f.Minute.Step == 0:
+		parts = append(parts, "of every hour")
+	case f.Hour.Any:
+	case f.Hour.Step > 0:
+		part := fmt.Sprintf("every %d hours", f.Hour.Step)
+		if !f.Hour.Whole {
+			part += fmt.Sprintf(" between %s and %s", clock(f.Hour.From, 0, 0), clock(f.Hour.To, 59, 0))
+		}
+		parts = append(parts, part)
+	default:
+		ranges := make([]string, len(f.Hour.Spans))
+		for i, span := range f.Hour.Spans {
+			ranges[i] = clock(span.From, 0, 0) + " and " + clock(span.To, 59, 0)
+		}
+		if hourly {
+			parts = append(parts, "every hour")
+		}
+		parts = append(parts, "between "+strings.Join(ranges, ", between "))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (l english) days(f Fields) string {
+	var doms, dows []string
+	if !f.Dom.Any {
+		doms = append(doms, l.clause(f.Dom, "day", strconv.Itoa))
+	}
+	if f.LastDom {
+		doms = append(doms, "the last day")
+	}
+	if f.LastWeekday {
+		doms = append(doms, "the last weekday")
+	}
+	for _, day := range f.NearestWeekday {
+		doms = append(doms, "the weekday nearest day "+strconv.Itoa(day))
+	}
+
+	weekday := func(wd int) string { return time.Weekday(wd).String() }
+	if !f.Dow.Any {
+		if span := f.Dow.Spans[0]; len(f.Dow.Spans) == 1 && span.From != span.To && len(f.LastDow)+len(f.NthDow) == 0 && !f.DomRestricted() {
+			// e.g. "Monday through Friday"
+			dows = append(dows, enSpans(f.Dow.Spans, weekday))
+		} else {
+			dows = append(dows, "on "+enSpans(f.Dow.Spans, weekday))
+		}
+	}
+	for _, wd := range f.LastDow {
+		dows = append(dows, "on the last "+weekday(wd)+" of the month")
+	}
+	for _, nth := range f.NthDow {
+		dows = append(dows, "on the "+enOrdinals[nth[1]]+" "+weekday(nth[0])+" of the month")
+	}
+
+	var parts []string
+	if len(doms) > 0 {
+		parts = append(parts, "on "+enJoin(doms)+" of the month")
+	}
+	if len(dows) > 0 {
+		parts = append(parts, strings.Join(dows, " or "))
+	}
+	return strings.Join(parts, " or ")
+}
+
+func (l english) months(v Values) string {
+	if v.Any {
+		return ""
+	}
+	if v.Step > 0 && v.Whole {
+		return fmt.Sprintf("every %d months", v.Step)
+	}
+	return "in " + enSpans(v.Spans, func(m int) string { return time.Month(m).String() })
+}
+
+func (l english) years(v Values) string {
+	if v.Any {
+		return ""
+	}
+	if v.Step > 0 {
+		return fmt.Sprintf("every %d years from %d through %d", v.Step, v.From, v.To)
+	}
+	return "in " + enSpans(v.Spans, strconv.Itoa)
+}
+
+// clause describes the values of the unit, e.g. "every 15 minutes", "at minute 5 and 30".
+func (english) clause(v Values, unit string, format func(int) string) string {
+	if v.Step > 0 {
+		s := fmt.Sprintf("every %d %ss", v.Step, unit)
+		if !v.Whole {
+			s += fmt.Sprintf(" from %s %s through %s", unit, format(v.From), format(v.To))
+		}
+		return s
+	}
+	if unit == "day" {
+		return unit + " " + enSpans(v.Spans, format)
+	}
+	return "at " + unit + " " + enSpans(v.Spans, format)
+}
+
+func enSpans(spans []Span, format func(int) string) string {
+	items := make([]string, len(spans))
+	for i, span := range spans {
+		if items[i] = format(span.From); span.To != span.From {
+			items[i] += " through " + format(span.To)
+		}
+	}
+	return enJoin(items)
+}
+
+// enJoin joins the items as "a, b and c".
+func enJoin(items []string) string {
+	if n := len(items); n > 1 {
+		return strings.Join(items[:n-1], ", ") + " and " + items[n-1]
+	}
+	return strings.Join(items, "")
+}
+
+func singles(spans []Span) bool {
+	for _, span := range spans {
+		if span.From != span.To {
+			return false
+		}
+	}
+	return true
+}
+
+// shortDuration formats d without the zero units, e.g. "1h30m" for "1h30m0s".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+func clock(h, m, s int) string {
+	if s != 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", h, m)
+}
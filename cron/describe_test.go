@@ -0,0 +1,21 @@
+package cron
+
+import "testing"
+
+func TestDescribe(t *testing.T) {
+	for _, c := range []struct {
+		spec   string
+		locale Locale
+		want   string
+	}{
+		{"0 */15 9-17 * * 1-5", English, "every 15 minutes between 09:00 and 17:59, Monday through Friday"},
+		{"0 */15 9-17 * * 1-5", Chinese, "周一至周五，09:00至17:59之间每15分钟"},
+		{"0 30 2 L * ?", English, "at 02:30, on the last day of the month"},
+		{"0 0 9 ? * 1#2", Chinese, "每月第二个周一，09:00"},
+		{"@every 1h30m", English, "every 1h30m"},
+	} {
+		if got, err := Describe(c.spec, c.locale); err != nil || got != c.want {
+			t.Errorf("%s: got %q (%v), want %q", c.spec, got, err, c.want)
+		}
+	}
+}
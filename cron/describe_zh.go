@@ -0,0 +1,190 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chinese describes the schedules in Chinese.
+var Chinese Locale = chinese{}
+
+type chinese struct{}
+
+var (
+	zhWeekdays = []string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
+	zhOrdinals = []string{"", "第一个", "第二个", "第三个", "第四个", "第五个"}
+)
+
+func (chinese) Every(d time.Duration) string { return "每隔" + shortDuration(d) }
+
+func (chinese) Once(t time.Time) string { return "在" + t.Format(time.DateTime) + "执行一次" }
+
+func (l chinese) Describe(f Fields) string {
+	var parts []string
+	for _, part := range []string{l.years(f.Year), l.months(f.Month), l.days(f)} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	at, daily := l.time(f)
+	if len(parts) == 0 && daily {
+		parts = append(parts, "每天")
+	}
+	s := strings.Join(append(parts, at), "，")
+	if f.Location != nil {
+		s += "（" + f.Location.String() + "）"
+	}
+	return s
+}
+
+// time describes the time of the day, daily reports whether it is a few times of the day.
+func (l chinese) time(f Fields) (at string, daily bool) {
+	h, hok := f.Hour.Single()
+	m, mok := f.Minute.Single()
+	s, sok := f.Second.Single()
+	if hok && mok && sok {
+		return clock(h, m, s), true
+	}
+
+	if mok && sok && !f.Hour.Any && f.Hour.Step == 0 && len(f.Hour.Spans) <= 4 && singles(f.Hour.Spans) {
+		times := make([]string, len(f.Hour.Spans))
+		for i, span := range f.Hour.Spans {
+			times[i] = clock(span.From, m, s)
+		}
+		return zhJoin(times), true
+	}
+
+	var parts []string
+	hourly := mok && sok && m == 0 && s == 0 // on the hour, e.g. "每2小时"
+	switch {
+	case hourly && f.Hour.Any:
+		parts = append(parts, "每小时")
+	case f.Hour.Any && !f.Minute.Any && f.Minute.Step == 0:
+		parts = append(parts, "每小时")
+	case f.Hour.Any:
+	case f.Hour.Step > 0:
+		part := fmt.Sprintf("每%d小时", f.Hour.Step)
+		if !f.Hour.Whole {
+			part = fmt.Sprintf("%s至%s之间", clock(f.Hour.From, 0, 0), clock(f.Hour.To, 59, 0)) + part
+		}
+		parts = append(parts, part)
+	default:
+		ranges := make([]string, len(f.Hour.Spans))
+		for i, span := range f.Hour.Spans {
+			ranges[i] = clock(span.From, 0, 0) + "至" + clock(span.To, 59, 0)
+		}
+		parts = append(parts, zhJoin(ranges)+"之间")
+		if hourly {
+			parts = append(parts, "每小时")
+		}
+	}
+
+	switch {
+	case hourly:
+	case f.Minute.Any && (f.Second.Any || sok && s == 0):
+		if !f.Second.Any {
+			parts = append(parts, "每分钟")
+		}
+	case f.Minute.Any:
+		parts = append(parts, "每分钟")
+	default:
+		parts = append(parts, l.clause(f.Minute, "分钟", "分"))
+	}
+
+	switch {
+	case sok && s == 0:
+	case f.Second.Any:
+		parts = append(parts, "每秒")
+	default:
+		parts = append(parts, l.clause(f.Second, "秒", "秒"))
+	}
+	return strings.Join(parts, ""), false
+}
+
+func (l chinese) days(f Fields) string {
+	var doms, dows []string
+	if !f.Dom.Any {
+		doms = append(doms, l.clause(f.Dom, "天", "日"))
+	}
+	if f.LastDom {
+		doms = append(doms, "最后一天")
+	}
+	if f.LastWeekday {
+		doms = append(doms, "最后一个工作日")
+	}
+	for _, day := range f.NearestWeekday {
+		doms = append(doms, "离"+strconv.Itoa(day)+"日最近的工作日")
+	}
+
+	weekday := func(wd int) string { return zhWeekdays[wd] }
+	if !f.Dow.Any {
+		dows = append(dows, zhSpans(f.Dow.Spans, weekday))
+	}
+	for _, wd := range f.LastDow {
+		dows = append(dows, "每月最后一个"+weekday(wd))
+	}
+	for _, nth := range f.NthDow {
+		dows = append(dows, "每月"+zhOrdinals[nth[1]]+weekday(nth[0]))
+	}
+
+	var parts []string
+	if len(doms) > 0 {
+		parts = append(parts, "每月"+zhJoin(doms))
+	}
+	if len(dows) > 0 {
+		parts = append(parts, strings.Join(dows, "或"))
+	}
+	return strings.Join(parts, "或")
+}
+
+func (l chinese) months(v Values) string {
+	if v.Any {
+		return ""
+	}
+	if v.Step > 0 && v.Whole {
+		return fmt.Sprintf("每%d个月", v.Step)
+	}
+	return zhSpans(v.Spans, func(m int) string { return strconv.Itoa(m) + "月" })
+}
+
+func (l chinese) years(v Values) string {
+	if v.Any {
+		return ""
+	}
+	if v.Step > 0 {
+		return fmt.Sprintf("%d年至%d年每%d年", v.From, v.To, v.Step)
+	}
+	return zhSpans(v.Spans, func(y int) string { return strconv.Itoa(y) + "年" })
+}
+
+// clause describes the values of the unit, e.g. "每15分钟", "第5、30分".
+func (chinese) clause(v Values, unit, suffix string) string {
+	if v.Step > 0 {
+		s := fmt.Sprintf("每%d%s", v.Step, unit)
+		if !v.Whole {
+			s = fmt.Sprintf("第%d至%d%s", v.From, v.To, suffix) + s
+		}
+		return s
+	}
+	return "第" + zhSpans(v.Spans, strconv.Itoa) + suffix
+}
+
+func zhSpans(spans []Span, format func(int) string) string {
+	items := make([]string, len(spans))
+	for i, span := range spans {
+		if items[i] = format(span.From); span.To != span.From {
+			items[i] += "至" + format(span.To)
+		}
+	}
+	return zhJoin(items)
+}
+
+// zhJoin joins the items as "a、b和c".
+func zhJoin(items []string) string {
+	if n := len(items); n > 1 {
+		return strings.Join(items[:n-1], "、") + "和" + items[n-1]
+	}
+	return strings.Join(items, "")
+}
@@ -0,0 +1,16 @@
+//go:build !unix && !windows
+
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func lockFile(*os.File) error {
+	return fmt.Errorf("file lock is not supported on %s: %w", runtime.GOOS, errors.ErrUnsupported)
+}
+
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,78 @@
+package cron
+
+import "time"
+
+const defaultHistory = 10 // executions kept by an entry, see WithHistory
+
+// Execution is a run of an entry, or a tick skipped by the overlap policy or claimed by
+// another scheduler.
+type Execution struct {
+	Tick     time.Time     // the scheduled time
+	Start    time.Time     // zero if skipped
+	End      time.Time     // zero if skipped
+	Duration time.Duration // End - Start
+	Err      error         // the error of the job, a *PanicError if it panicked
+	Skipped  bool
+}
+
+// Stats are the counters of the executions of an entry, skipped ticks excluded.
+type Stats struct {
+	Runs        uint64
+	Failures    uint64        // runs returned an error or panicked
+	Consecutive uint64        // failures since the last success
+	Total       time.Duration // sum of the durations
+	Max         time.Duration
+	LastSuccess time.Time // end of the last successful run
+	LastFailure time.Time // end of the last failed run
+}
+
+// Avg returns the average duration of the runs.
+func (s Stats) Avg() time.Duration {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Runs)
+}
+
+// WithHistory sets the number of the recent executions kept by the entry, defaults to 10,
+// 0 keeps none, the stats are counted anyway.
+func WithHistory(n int) EntryOption { return func(e *Entry) { e.historySize = max(n, 0) } }
+
+// record adds the execution to the history and the stats, c.mu must be held.
+func (e *Entry) record(x Execution) {
+	if !x.Skipped {
+		x.Duration = x.End.Sub(x.Start)
+		s := &e.Stats
+		s.Runs++
+		s.Total += x.Duration
+		s.Max = max(s.Max, x.Duration)
+		if x.Err != nil {
+			s.Failures++
+			s.Consecutive++
+			s.LastFailure = x.End
+		} else {
+			s.Consecutive = 0
+			s.LastSuccess = x.End
+		}
+	}
+
+	if e.historySize == 0 {
+		return
+	}
+	// A ring of historySize, history[head] is the oldest once it is full.
+	if len(e.history) < e.historySize {
+		e.history = append(e.history, x)
+		return
+	}
+	e.history[e.head] = x
+	e.head = (e.head + 1) % e.historySize
+}
+
+// snapshot returns a copy of the entry with History ordered oldest first, c.mu must be held.
+func (e *Entry) snapshot() Entry {
+	s := *e
+	s.History = make([]Execution, 0, len(e.history))
+	s.History = append(append(s.History, e.history[e.head:]...), e.history[:e.head]...)
+	s.history = nil
+	return s
+}
@@ -0,0 +1,160 @@
+package cron
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cnk3x/gox/chans"
+)
+
+// Locker grants the ticks of the entries to one of the schedulers sharing it, see WithLocker.
+type Locker interface {
+	// Lock claims the tick of the key with a lease for ttl. It reports false if the tick, or
+	// a later one, is claimed, or the key is held by an unexpired lease. The fencing token
+	// increases with each claim of the key.
+	Lock(ctx context.Context, key string, tick time.Time, ttl time.Duration) (token uint64, ok bool, err error)
+	// Unlock releases the lease of the token, the tick stays claimed. A lease expired and
+	// claimed again by another is not released.
+	Unlock(ctx context.Context, key string, token uint64) error
+}
+
+type tokenKey struct{}
+
+// LockToken returns the fencing token of the run, if the job runs under a Locker. The job
+// passes it to the resources it writes, which reject the tokens older than one they have
+// seen, e.g. after the lease expired and the tick was claimed by another.
+func LockToken(ctx context.Context) (token uint64, ok bool) {
+	token, ok = ctx.Value(tokenKey{}).(uint64)
+	return
+}
+
+// lease is the state of a key of the lockers.
+type lease struct {
+	Tick    time.Time `json:"tick"`    // the last claimed tick
+	Token   uint64    `json:"token"`   // fencing token of the last claim
+	Expires time.Time `json:"expires"` // zero if released
+}
+
+// claim claims the tick for ttl, it reports false if the tick is not available.
+func (l *lease) claim(now, tick time.Time, ttl time.Duration) bool {
+	if !l.Tick.Before(tick) || now.Before(l.Expires) {
+		return false
+	}
+	l.Tick, l.Expires = tick, now.Add(ttl)
+	l.Token++
+	return true
+}
+
+// release releases the lease of the token.
+func (l *lease) release(token uint64) {
+	if l.Token == token {
+		l.Expires = time.Time{}
+	}
+}
+
+// MemoryLocker is a Locker of the schedulers in one process.
+type MemoryLocker struct {
+	Clock chans.Clock // nil for chans.SystemClock
+
+	leases map[string]*lease
+	mu     sync.Mutex
+}
+
+func NewMemoryLocker() *MemoryLocker { return &MemoryLocker{leases: map[string]*lease{}} }
+
+func (l *MemoryLocker) Lock(_ context.Context, key string, tick time.Time, ttl time.Duration) (token uint64, ok bool, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r := l.leases[key]
+	if r == nil {
+		r = &lease{}
+		l.leases[key] = r
+	}
+	ok = r.claim(cmp.Or[chans.Clock](l.Clock, chans.SystemClock).Now(), tick, ttl)
+	return r.Token, ok, nil
+}
+
+func (l *MemoryLocker) Unlock(_ context.Context, key string, token uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r := l.leases[key]; r != nil {
+		r.release(token)
+	}
+	return nil
+}
+
+// FileLocker is a Locker of the schedulers on one host, the leases are files in the directory,
+// updated under an exclusive file lock (flock on unix, LockFileEx on windows).
+type FileLocker struct {
+	Dir   string
+	Clock chans.Clock // nil for chans.SystemClock
+}
+
+func NewFileLocker(dir string) *FileLocker { return &FileLocker{Dir: dir} }
+
+func (l *FileLocker) Lock(_ context.Context, key string, tick time.Time, ttl time.Duration) (token uint64, ok bool, err error) {
+	err = l.update(key, func(r *lease) bool {
+		ok = r.claim(cmp.Or[chans.Clock](l.Clock, chans.SystemClock).Now(), tick, ttl)
+		token = r.Token
+		return ok
+	})
+	return
+}
+
+func (l *FileLocker) Unlock(_ context.Context, key string, token uint64) error {
+	return l.update(key, func(r *lease) bool {
+		r.release(token)
+		return r.Token == token
+	})
+}
+
+// update reads the lease of the key under the file lock, and writes it back if fn reports true.
+func (l *FileLocker) update(key string, fn func(r *lease) bool) (err error) {
+	if err = os.MkdirAll(l.Dir, 0o777); err != nil {
+		return
+	}
+
+	f, err := os.OpenFile(filepath.Join(l.Dir, url.QueryEscape(key)+".lock"), os.O_RDWR|os.O_CREATE, 0o666)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	if err = lockFile(f); err != nil {
+		return
+	}
+	defer unlockFile(f)
+
+	var r lease
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return
+	}
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &r); err != nil {
+			return
+		}
+	}
+
+	if !fn(&r) {
+		return
+	}
+
+	if data, err = json.Marshal(r); err != nil {
+		return
+	}
+	if err = f.Truncate(0); err == nil {
+		_, err = f.WriteAt(data, 0)
+	}
+	return errors.Join(err, f.Sync())
+}
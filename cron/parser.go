@@ -1,11 +1,14 @@
 package cron
 
 import (
+	"cmp"
 	"fmt"
 	"math"
+	"slices"
 	"strconv"
 	"time"
 
+	"github.com/cnk3x/gox/chans"
 	"github.com/cnk3x/gox/strs"
 )
 
@@ -25,6 +28,7 @@ const (
 	Dow                                    // Day of week field, default *
 	DowOptional                            // Optional day of week field, default *
 	Descriptor                             // Allow descriptors such as @monthly, @weekly, etc.
+	YearOptional                           // Optional year field after all other fields, default *
 )
 
 var places = []ParseOption{
@@ -47,7 +51,9 @@ var defaults = []string{
 
 // A custom Parser that can be configured.
 type Parser struct {
-	options ParseOption
+	options     ParseOption
+	searchYears int
+	clock       chans.Clock // the time of @once, nil for chans.SystemClock
 }
 
 // NewParser creates a Parser with custom options.
@@ -79,7 +85,20 @@ func NewParser(options ParseOption) Parser {
 	if optionals > 1 {
 		panic("multiple optionals may not be configured")
 	}
-	return Parser{options}
+	return Parser{options: options}
+}
+
+// WithClock returns a copy of the parser, its @once schedules start at the time of the clock.
+func (p Parser) WithClock(clock chans.Clock) Parser {
+	p.clock = clock
+	return p
+}
+
+// WithSearchYears returns a copy of the parser, its schedules search the next time within
+// the years, see SpecSchedule.SearchYears.
+func (p Parser) WithSearchYears(years int) Parser {
+	p.searchYears = years
+	return p
 }
 
 // Parse returns a new crontab schedule representing the given spec.
@@ -107,7 +126,7 @@ func (p Parser) Parse(spec string) (Schedule, error) {
 		if p.options&Descriptor == 0 {
 			return nil, fmt.Errorf("parser does not accept descriptors: %v", spec)
 		}
-		return parseDescriptor(spec, loc)
+		return parseDescriptor(spec, loc, cmp.Or[chans.Clock](p.clock, chans.SystemClock).Now())
 	}
 
 	// Split on whitespace.
@@ -120,40 +139,33 @@ func (p Parser) Parse(spec string) (Schedule, error) {
 		return nil, err
 	}
 
-	field := func(field string, r bounds) uint64 {
+	schedule := &SpecSchedule{Location: loc, SearchYears: p.searchYears}
+	field := func(field string, r bounds, rules ...func(expr string) (bool, error)) uint64 {
 		if err != nil {
 			return 0
 		}
 		var bits uint64
-		bits, err = getField(field, r)
+		bits, err = getField(field, r, rules...)
 		return bits
 	}
 
-	var (
-		second     = field(fields[0], seconds)
-		minute     = field(fields[1], minutes)
-		hour       = field(fields[2], hours)
-		dayofmonth = field(fields[3], dom)
-		month      = field(fields[4], months)
-		dayofweek  = field(fields[5], dow)
-	)
+	schedule.Second = field(fields[0], seconds)
+	schedule.Minute = field(fields[1], minutes)
+	schedule.Hour = field(fields[2], hours)
+	schedule.Dom = field(fields[3], dom, schedule.parseDomRule)
+	schedule.Month = field(fields[4], months)
+	schedule.Dow = field(fields[5], dow, schedule.parseDowRule)
+	if err == nil {
+		schedule.Years, err = getYears(fields[6])
+	}
 	if err != nil {
 		return nil, err
 	}
-
-	return &SpecSchedule{
-		Second:   second,
-		Minute:   minute,
-		Hour:     hour,
-		Dom:      dayofmonth,
-		Month:    month,
-		Dow:      dayofweek,
-		Location: loc,
-	}, nil
+	return schedule, nil
 }
 
 // normalizeFields takes a subset set of the time fields and returns the full set
-// with defaults (zeroes) populated for unset fields.
+// with defaults (zeroes) populated for unset fields, followed by the year field.
 //
 // As part of performing this function, it also validates that the provided
 // fields are compatible with the configured options.
@@ -181,8 +193,17 @@ func normalizeFields(fields []string, options ParseOption) ([]string, error) {
 	}
 	min := max - optionals
 
+	// The optional year field is present only if all other fields are.
+	year := "*"
+	if options&YearOptional > 0 && len(fields) == max+1 {
+		year, fields = fields[max], fields[:max]
+	}
+
 	// Validate number of fields
 	if count := len(fields); count < min || count > max {
+		if options&YearOptional > 0 {
+			max++
+		}
 		if min == max {
 			return nil, fmt.Errorf("expected exactly %d fields, found %d: %s", min, count, fields)
 		}
@@ -211,12 +232,12 @@ func normalizeFields(fields []string, options ParseOption) ([]string, error) {
 			n++
 		}
 	}
-	return expandedFields, nil
+	return append(expandedFields, year), nil
 }
 
 var (
 	standardParser = NewParser(Minute | Hour | Dom | Month | Dow | Descriptor)
-	optionalParser = NewParser(SecondOptional | Minute | Hour | Dom | Month | Dow | Descriptor)
+	optionalParser = NewParser(SecondOptional | Minute | Hour | Dom | Month | Dow | Descriptor | YearOptional)
 )
 
 // ParseStandard returns a new crontab schedule representing the given
@@ -233,11 +254,21 @@ func ParseStandard(standardSpec string) (Schedule, error) {
 
 // getField returns an Int with the bits set representing all of the times that
 // the field represents or error parsing field value.  A "field" is a comma-separated
-// list of "ranges".
-func getField(field string, r bounds) (uint64, error) {
+// list of "ranges". The rule parses an expression not a range, e.g. "L", it reports
+// whether the expression is handled.
+func getField(field string, r bounds, rules ...func(expr string) (bool, error)) (uint64, error) {
 	var bits uint64
 	ranges := strs.FieldsFunc(field, func(r rune) bool { return r == ',' })
+NEXT:
 	for _, expr := range ranges {
+		for _, rule := range rules {
+			if ok, err := rule(expr); err != nil {
+				return bits, err
+			} else if ok {
+				continue NEXT
+			}
+		}
+
 		bit, err := getRange(expr, r)
 		if err != nil {
 			return bits, err
@@ -320,6 +351,137 @@ func getRange(expr string, r bounds) (uint64, error) {
 	return getBits(start, end, step) | extra, nil
 }
 
+// getYears returns the sorted years of the year field, nil for any year. The field is a
+// comma-separated list of ranges as other fields, e.g. "2027", "2027-2030", "2026/2".
+func getYears(field string) (years []int, err error) {
+	set := map[int]bool{}
+	for _, expr := range strs.FieldsFunc(field, func(r rune) bool { return r == ',' }) {
+		rangeAndStep := strs.Split(expr, "/")
+		lowAndHigh := strs.Split(rangeAndStep[0], "-")
+		start, end, step := yearBounds.min, yearBounds.max, uint(1)
+
+		if lowAndHigh[0] == "*" || lowAndHigh[0] == "?" {
+			if len(rangeAndStep) == 1 {
+				return nil, nil
+			}
+		} else {
+			if start, err = mustParseInt(lowAndHigh[0]); err != nil {
+				return
+			}
+			switch {
+			case len(lowAndHigh) == 2:
+				if end, err = mustParseInt(lowAndHigh[1]); err != nil {
+					return
+				}
+			case len(lowAndHigh) > 2:
+				return nil, fmt.Errorf("too many hyphens: %s", expr)
+			case len(rangeAndStep) == 1:
+				end = start
+			}
+		}
+
+		switch len(rangeAndStep) {
+		case 1:
+		case 2:
+			if step, err = mustParseInt(rangeAndStep[1]); err != nil {
+				return
+			}
+		default:
+			return nil, fmt.Errorf("too many slashes: %s", expr)
+		}
+
+		switch {
+		case start < yearBounds.min:
+			return nil, fmt.Errorf("beginning of range (%d) below minimum (%d): %s", start, yearBounds.min, expr)
+		case end > yearBounds.max:
+			return nil, fmt.Errorf("end of range (%d) above maximum (%d): %s", end, yearBounds.max, expr)
+		case start > end:
+			return nil, fmt.Errorf("beginning of range (%d) beyond end of range (%d): %s", start, end, expr)
+		case step == 0:
+			return nil, fmt.Errorf("step of range should be a positive number: %s", expr)
+		}
+
+		for y := start; y <= end; y += step {
+			set[int(y)] = true
+		}
+	}
+
+	for y := range set {
+		years = append(years, y)
+	}
+	slices.Sort(years)
+	return
+}
+
+// parseDomRule parses the Quartz-style day of month expressions:
+//
+//	L   the last day of the month
+//	LW  the last weekday (Monday to Friday) of the month
+//	nW  the weekday nearest to the day n, in the same month
+func (s *SpecSchedule) parseDomRule(expr string) (bool, error) {
+	switch expr = strs.Upper(expr); {
+	case expr == "L":
+		s.LastDom = true
+	case expr == "LW" || expr == "WL":
+		s.LastWeekday = true
+	case strs.HasSuffix(expr, "W"):
+		day, err := mustParseInt(strs.TrimSuffix(expr, "W"))
+		if err != nil {
+			return false, err
+		}
+		if day < dom.min || day > dom.max {
+			return false, fmt.Errorf("day (%d) out of range [%d, %d]: %s", day, dom.min, dom.max, expr)
+		}
+		s.NearestWeekday |= 1 << day
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// parseDowRule parses the Quartz-style day of week expressions:
+//
+//	nL   the last weekday n of the month, e.g. 5L the last Friday
+//	n#k  the k-th weekday n of the month, e.g. 1#2 the second Monday
+func (s *SpecSchedule) parseDowRule(expr string) (bool, error) {
+	weekday := func(expr string) (uint, error) {
+		wd, err := parseIntOrName(expr, dow.names)
+		if err == nil && wd > dow.max {
+			err = fmt.Errorf("weekday (%d) above maximum (%d): %s", wd, dow.max, expr)
+		}
+		return wd, err
+	}
+
+	if day, nth, found := strs.Cut(expr, "#"); found {
+		wd, err := weekday(day)
+		if err != nil {
+			return false, err
+		}
+		k, err := mustParseInt(nth)
+		if err != nil {
+			return false, err
+		}
+		if k < 1 || k > 5 {
+			return false, fmt.Errorf("nth weekday (%d) out of range [1, 5]: %s", k, expr)
+		}
+		s.NthDow[wd] |= 1 << k
+		return true, nil
+	}
+
+	if upper := strs.Upper(expr); strs.HasSuffix(upper, "L") {
+		if upper == "L" {
+			return false, fmt.Errorf("L of day of week needs a weekday, e.g. 5L: %s", expr)
+		}
+		wd, err := weekday(strs.Lower(strs.TrimSuffix(upper, "L")))
+		if err != nil {
+			return false, err
+		}
+		s.LastDow |= 1 << wd
+		return true, nil
+	}
+	return false, nil
+}
+
 // parseIntOrName returns the (possibly-named) integer contained in expr.
 func parseIntOrName(expr string, names map[string]uint) (uint, error) {
 	if names != nil {
@@ -365,7 +527,8 @@ func all(r bounds) uint64 {
 }
 
 // parseDescriptor returns a predefined schedule for the expression, or error if none matches.
-func parseDescriptor(descriptor string, loc *time.Location) (Schedule, error) {
+// The now is the start time of @once.
+func parseDescriptor(descriptor string, loc *time.Location, now time.Time) (Schedule, error) {
 	switch descriptor {
 	case "@yearly", "@annually":
 		return &SpecSchedule{
@@ -439,7 +602,7 @@ func parseDescriptor(descriptor string, loc *time.Location) (Schedule, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse duration %s: %s", descriptor, err)
 		}
-		return Once(duration), nil
+		return OnceSchedule{Time: now.Add(duration)}, nil
 	}
 
 	return nil, fmt.Errorf("unrecognized descriptor: %s", descriptor)
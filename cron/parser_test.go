@@ -0,0 +1,96 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuartzDays(t *testing.T) {
+	from := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		spec string
+		want []string
+	}{
+		{"0 0 0 L * *", []string{"2026-01-31", "2026-02-28", "2026-03-31"}},
+		{"0 0 0 LW * *", []string{"2026-01-30", "2026-02-27", "2026-03-31"}},
+		{"0 0 0 1W * *", []string{"2026-01-01", "2026-02-02", "2026-03-02"}},
+		{"0 0 0 15W * *", []string{"2026-01-15", "2026-02-16", "2026-03-16"}},
+		{"0 0 0 ? * 5L", []string{"2026-01-30", "2026-02-27", "2026-03-27"}},
+		{"0 0 0 ? * 1#2", []string{"2026-01-12", "2026-02-09", "2026-03-09"}},
+		{"0 0 0 ? * MON#1,FRIL", []string{"2026-01-05", "2026-01-30", "2026-02-02"}},
+	} {
+		s, err := optionalParser.Parse("CRON_TZ=UTC " + c.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", c.spec, err)
+		}
+		next := from.Add(-time.Second)
+		for _, want := range c.want {
+			if next = s.Next(next); next.Format(time.DateOnly) != want {
+				t.Errorf("%s: got %s, want %s", c.spec, next.Format(time.DateOnly), want)
+			}
+		}
+	}
+
+	for _, spec := range []string{"0 0 0 32W * *", "0 0 0 ? * L", "0 0 0 ? * 1#6", "0 0 0 ? * 8L"} {
+		if _, err := optionalParser.Parse(spec); err == nil {
+			t.Errorf("%s: want error", spec)
+		}
+	}
+}
+
+func TestYears(t *testing.T) {
+	from := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		spec string
+		want []string
+	}{
+		{"0 0 0 ? * SUN 2027", []string{"2027-01-03", "2027-01-10"}},
+		{"0 0 0 1 1 ? 2040", []string{"2040-01-01", "0001-01-01"}},
+		{"0 0 0 1 1 ? 2026-2030/2", []string{"2028-01-01", "2030-01-01", "0001-01-01"}},
+		{"0 0 0 29 2 ?", []string{"2028-02-29", "2032-02-29"}},
+	} {
+		s, err := optionalParser.Parse("CRON_TZ=UTC " + c.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", c.spec, err)
+		}
+		next := from
+		for _, want := range c.want {
+			if next = s.Next(next); next.Format(time.DateOnly) != want {
+				t.Errorf("%s: got %s, want %s", c.spec, next.Format(time.DateOnly), want)
+			}
+		}
+	}
+
+	for _, spec := range []string{"0 0 0 1 1 ? 1969", "0 0 0 1 1 ? 2030-2027", "0 0 0 1 1 ? 2027 1"} {
+		if _, err := optionalParser.Parse(spec); err == nil {
+			t.Errorf("%s: want error", spec)
+		}
+	}
+}
+
+func TestPrevNextN(t *testing.T) {
+	s, err := optionalParser.Parse("CRON_TZ=UTC 0 0 9 ? * 1#2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2026, time.March, 9, 9, 0, 0, 0, time.UTC)
+	next := NextN(s, from, 3)
+	for i, want := range []string{"2026-04-13", "2026-05-11", "2026-06-08"} {
+		if got := next[i].Format(time.DateOnly); got != want {
+			t.Errorf("next #%d: got %s, want %s", i, got, want)
+		}
+	}
+
+	if next := NextN(s, from, -1); len(next) != 0 {
+		t.Errorf("next -1: got %v", next)
+	}
+
+	prev := s.(*SpecSchedule).Prev
+	if got := prev(from); got.Format(time.DateTime) != "2026-02-09 09:00:00" {
+		t.Errorf("prev of activation: got %s", got)
+	}
+	if got := prev(from.Add(time.Nanosecond)); !got.Equal(from) {
+		t.Errorf("prev after activation: got %s", got)
+	}
+}
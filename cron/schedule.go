@@ -1,6 +1,11 @@
 package cron
 
-import "time"
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"time"
+)
 
 // Schedule describes a job's duty cycle.
 type Schedule interface {
@@ -9,6 +14,28 @@ type Schedule interface {
 	Next(time.Time) time.Time
 }
 
+// NextN returns the next n activation times of the schedule after from, fewer if the
+// schedule ends, none if n <= 0.
+func NextN(schedule Schedule, from time.Time, n int) []time.Time {
+	n = max(n, 0)
+	times := make([]time.Time, 0, n)
+	for t := range Upcoming(schedule, from) {
+		if len(times) == n {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// Upcoming returns the activation times of the schedule after from, until the schedule ends.
+func Upcoming(schedule Schedule, from time.Time) iter.Seq[time.Time] {
+	return func(yield func(time.Time) bool) {
+		for t := schedule.Next(from); !t.IsZero() && yield(t); t = schedule.Next(t) {
+		}
+	}
+}
+
 // ConstantDelaySchedule represents a simple recurring duty cycle, e.g. "Every 5 minutes".
 // It does not support jobs more frequent than once a second.
 type ConstantDelaySchedule struct {
@@ -51,6 +78,20 @@ func Once(d time.Duration) Schedule { return OnceSchedule{Time: time.Now().Add(d
 type SpecSchedule struct {
 	Second, Minute, Hour, Dom, Month, Dow uint64
 
+	// Quartz-style day rules, matched in addition to Dom and Dow.
+	LastDom        bool     // L, the last day of the month
+	LastWeekday    bool     // LW, the last weekday of the month
+	NearestWeekday uint64   // nW, bits of the days, the weekday nearest to the day
+	LastDow        uint64   // nL, bits of the weekdays, the last one of the month
+	NthDow         [7]uint8 // n#k, bits of k by the weekday, the k-th one of the month
+
+	// Years are the sorted years of the optional year field, nil for any year.
+	Years []int
+
+	// SearchYears limits the search of Next, 0 for defaultSearchYears. The search is
+	// extended to the last year of Years.
+	SearchYears int
+
 	// Override location for this schedule.
 	Location *time.Location
 }
@@ -86,14 +127,29 @@ func (s *SpecSchedule) Next(t time.Time) time.Time {
 	// This flag indicates whether a field has been incremented.
 	added := false
 
-	// If no time is found within five years, return zero.
-	yearLimit := t.Year() + 5
+	// If no time is found within the search years, return zero.
+	yearLimit := t.Year() + cmp.Or(s.SearchYears, defaultSearchYears)
+	if n := len(s.Years); n > 0 {
+		yearLimit = max(yearLimit, s.Years[n-1])
+	}
 
 WRAP:
 	if t.Year() > yearLimit {
 		return time.Time{}
 	}
 
+	// Find the first applicable year.
+	if s.Years != nil {
+		i, found := slices.BinarySearch(s.Years, t.Year())
+		if !found {
+			if i == len(s.Years) {
+				return time.Time{}
+			}
+			added = true
+			t = time.Date(s.Years[i], time.January, 1, 0, 0, 0, 0, loc)
+		}
+	}
+
 	// Find the first applicable month.
 	// If it's this month, then do nothing.
 	for 1<<uint(t.Month())&s.Month == 0 {
@@ -176,6 +232,84 @@ WRAP:
 	return t.In(origLocation)
 }
 
+// Prev returns the last time this schedule was activated, less than the given time.
+// If no time can be found within the search years, return the zero time.
+func (s *SpecSchedule) Prev(t time.Time) time.Time {
+	// The mirror of Next: when a field doesn't match, move to the last second of the
+	// previous value of the field, and wrap around to re-verify the larger fields.
+	origLocation := t.Location()
+	loc := s.Location
+	if loc == time.Local {
+		loc = t.Location()
+	}
+	if s.Location != time.Local {
+		t = t.In(s.Location)
+	}
+
+	// Start at the latest possible time (the last whole second before t).
+	if prev := t.Truncate(time.Second); prev.Equal(t) {
+		t = prev.Add(-time.Second)
+	} else {
+		t = prev
+	}
+
+	yearLimit := t.Year() - cmp.Or(s.SearchYears, defaultSearchYears)
+	if len(s.Years) > 0 {
+		yearLimit = min(yearLimit, s.Years[0])
+	}
+
+WRAP:
+	if t.Year() < yearLimit {
+		return time.Time{}
+	}
+
+	if s.Years != nil {
+		if i, found := slices.BinarySearch(s.Years, t.Year()); !found {
+			if i == 0 {
+				return time.Time{}
+			}
+			t = time.Date(s.Years[i-1]+1, time.January, 1, 0, 0, 0, 0, loc).Add(-time.Second)
+		}
+	}
+
+	for 1<<uint(t.Month())&s.Month == 0 {
+		t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc).Add(-time.Second)
+		if t.Month() == time.December {
+			goto WRAP
+		}
+	}
+
+	for !dayMatches(s, t) {
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc).Add(-time.Second)
+		if t.Day() == daysIn(t) {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.Hour == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc).Add(-time.Second)
+		if t.Hour() == 23 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.Minute == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(-time.Second)
+		if t.Minute() == 59 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Second())&s.Second == 0 {
+		t = t.Add(-time.Second)
+		if t.Second() == 59 {
+			goto WRAP
+		}
+	}
+
+	return t.In(origLocation)
+}
+
 // bounds provides a range of acceptable values (plus a map of name to value).
 type bounds struct {
 	min, max uint
@@ -211,22 +345,79 @@ var (
 		"fri": 5,
 		"sat": 6,
 	}}
+	yearBounds = bounds{1970, 2099, nil}
 )
 
 const (
 	// Set the top bit if a star was included in the expression.
 	starBit = 1 << 63
+
+	// Default years to search the next time of a SpecSchedule.
+	defaultSearchYears = 5
 )
 
 // dayMatches returns true if the schedule's day-of-week and day-of-month
 // restrictions are satisfied by the given time.
 func dayMatches(s *SpecSchedule, t time.Time) bool {
 	var (
-		domMatch bool = 1<<uint(t.Day())&s.Dom > 0
-		dowMatch bool = 1<<uint(t.Weekday())&s.Dow > 0
+		domMatch bool = 1<<uint(t.Day())&s.Dom > 0 || domRuleMatches(s, t)
+		dowMatch bool = 1<<uint(t.Weekday())&s.Dow > 0 || dowRuleMatches(s, t)
 	)
 	if s.Dom&starBit > 0 || s.Dow&starBit > 0 {
 		return domMatch && dowMatch
 	}
 	return domMatch || dowMatch
 }
+
+// domRuleMatches returns true if the day of month is matched by L, LW or nW.
+func domRuleMatches(s *SpecSchedule, t time.Time) bool {
+	if !s.LastDom && !s.LastWeekday && s.NearestWeekday == 0 {
+		return false
+	}
+
+	day, last := t.Day(), daysIn(t)
+	if s.LastDom && day == last {
+		return true
+	}
+	if s.LastWeekday && day == nearestWeekday(t, last, last) {
+		return true
+	}
+	for n := 1; n <= last; n++ {
+		if 1<<uint(n)&s.NearestWeekday > 0 && day == nearestWeekday(t, n, last) {
+			return true
+		}
+	}
+	return false
+}
+
+// dowRuleMatches returns true if the day of week is matched by nL or n#k.
+func dowRuleMatches(s *SpecSchedule, t time.Time) bool {
+	wd, day := t.Weekday(), t.Day()
+	if 1<<uint(wd)&s.LastDow > 0 && day+7 > daysIn(t) {
+		return true
+	}
+	return 1<<uint((day-1)/7+1)&s.NthDow[wd] > 0
+}
+
+// daysIn returns the number of days in the month of t.
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nearestWeekday returns the weekday (Monday to Friday) nearest to the day in the month of t,
+// without crossing the month.
+func nearestWeekday(t time.Time, day, last int) int {
+	switch time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, time.UTC).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == last {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
@@ -0,0 +1,37 @@
+package cron
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// loadState reads the last run times by entry name, a missing file is not an error.
+func loadState(path string) (prevs map[string]time.Time, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	err = json.Unmarshal(data, &prevs)
+	return
+}
+
+// saveState writes the last run times by entry name, the file is replaced atomically.
+func saveState(path string, prevs map[string]time.Time) (err error) {
+	data, err := json.MarshalIndent(prevs, "", "  ")
+	if err != nil {
+		return
+	}
+
+	_ = os.MkdirAll(filepath.Dir(path), 0o777)
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o666); err != nil {
+		return
+	}
+	return os.Rename(tmp, path)
+}